@@ -0,0 +1,228 @@
+package app
+
+import (
+	"DelayedNotifier/internal/callback"
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/events"
+	"DelayedNotifier/internal/handlers"
+	"DelayedNotifier/internal/memory"
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/outbox"
+	"DelayedNotifier/internal/rabbitMQ"
+	"DelayedNotifier/internal/ratelimit"
+	"DelayedNotifier/internal/redisdb"
+	"DelayedNotifier/internal/scheduler"
+	"DelayedNotifier/internal/sender"
+	"DelayedNotifier/internal/worker"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Хранилища уведомлений (config.Storage.Backend)
+const (
+	StorageRedis  = "redis"
+	StorageMemory = "memory"
+)
+
+// Store хранилище уведомлений: redisdb.RedisConnection или memory.Store
+type Store interface {
+	handlers.RedisStore
+	worker.Store
+	outbox.Store
+	ratelimit.Store
+	callback.Store
+	events.Store
+	DeleteMessage(ctx context.Context, uuid string) error
+	Close()
+}
+
+var (
+	_ Store = (*redisdb.RedisConnection)(nil)
+	_ Store = (*memory.Store)(nil)
+)
+
+// App собранный сервис: HTTP API и фоновые обработчики поверх выбранных в конфиге бэкендов
+type App struct {
+	store   Store
+	queue   scheduler.Scheduler
+	events  *events.Broker
+	handler http.Handler
+	// callbacks отправка событий на callback_url включена: задан секрет подписи
+	callbacks bool
+	// фоновые обработчики, запускаются в Run
+	runners []func(ctx context.Context) error
+	closers []func()
+}
+
+// New собирает сервис по конфигу. ctx ограничивает жизнь соединений и передаётся обработчикам API.
+func New(ctx context.Context, cfg *config.Config) (*App, error) {
+	a := &App{}
+
+	switch cfg.Storage.Backend {
+	case StorageRedis:
+		if cfg.Port == "" {
+			return nil, errors.New("db_path.port is required for redis storage")
+		}
+		// Radis connection init
+		a.store = redisdb.DeclareRedisDataBase(redis.Options{
+			Addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+			Password: cfg.Password,
+			DB:       0,
+			Protocol: 2,
+		})
+	case StorageMemory:
+		a.store = memory.NewStore()
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+	a.closers = append(a.closers, a.store.Close)
+
+	// каналы доставки
+	senders := sender.NewRegistry()
+	if cfg.SMTP.Host != "" {
+		senders.Register(models.ChannelEmail, sender.NewSMTP(cfg.SMTP))
+	}
+	if cfg.Telegram.Token != "" {
+		senders.Register(models.ChannelTelegram, sender.NewTelegram(cfg.Telegram))
+	}
+	if cfg.Webhook.Secret != "" {
+		senders.Register(models.ChannelWebhook, sender.NewWebhook(cfg.Webhook))
+	}
+
+	if err := a.openQueue(ctx, cfg, senders); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	// созданные уведомления публикует в очередь outbox relay; reconciler возвращает
+	// в outbox уведомления, потерявшие сообщение после сбоев
+	relay := outbox.NewRelay(a.store, a.queue, cfg.Outbox.PollInterval, cfg.Outbox.BatchSize, cfg.Outbox.RetryDelay, cfg.Outbox.PublishConcurrency)
+	reconciler := outbox.NewReconciler(a.store, cfg.Outbox.ReconcileInterval, cfg.Outbox.ReconcileGrace, cfg.Outbox.ProcessingTimeout)
+	a.runners = append(a.runners, relay.Run, reconciler.Run)
+
+	// события смены статуса для callback_url уведомлений; без секрета подписать их нечем,
+	// поэтому уведомления с callback_url не принимаются
+	if cfg.Callback.Secret != "" {
+		a.callbacks = true
+		a.runners = append(a.runners, callback.NewDispatcher(a.store, cfg.Callback).Run)
+	}
+
+	// одна подписка на события смены статуса на процесс, её раздают клиентам GET /notify/events
+	a.events = events.NewBroker(a.store)
+	a.runners = append(a.runners, a.events.Run)
+
+	a.handler = a.routes(ctx)
+	return a, nil
+}
+
+// openQueue подключает планировщик и обработчики наступивших уведомлений
+func (a *App) openQueue(ctx context.Context, cfg *config.Config, senders *sender.Registry) error {
+	retry := worker.RetryPolicy{
+		BaseDelay:   cfg.Worker.RetryBaseDelay,
+		MaxDelay:    cfg.Worker.RetryMaxDelay,
+		MaxAttempts: cfg.Worker.MaxAttempts,
+	}
+	// корзины лимитов лежат в общем хранилище, поэтому лимиты действуют на все экземпляры сервиса
+	limiter := ratelimit.New(a.store, cfg.RateLimit)
+
+	if cfg.Scheduler.Backend == scheduler.BackendMemory {
+		queue := memory.NewQueue(cfg.Scheduler.Tick)
+		a.queue = queue
+		notifyWorker := worker.New(a.store, queue, senders, retry, limiter)
+		a.runners = append(a.runners, func(ctx context.Context) error {
+			return queue.Run(ctx, cfg.Worker.Concurrency, notifyWorker.Handle, notifyWorker.HandleDead)
+		})
+		return nil
+	}
+
+	if cfg.Scheduler.Backend != scheduler.BackendRabbitMQ && cfg.Scheduler.Backend != scheduler.BackendRedis {
+		return fmt.Errorf("unknown scheduler backend %q", cfg.Scheduler.Backend)
+	}
+	rdb, isRedis := a.store.(*redisdb.RedisConnection)
+	if cfg.Scheduler.Backend == scheduler.BackendRedis && !isRedis {
+		return errors.New("redis scheduler requires redis storage")
+	}
+
+	// rabbitMQ init: соединение само восстанавливается после обрыва и объявляет топологию;
+	// delayed exchange нужен только планировщику на плагине
+	delayed := cfg.Scheduler.Backend == scheduler.BackendRabbitMQ
+	conn, err := rabbitMQ.Dial(ctx, cfg.Broker.URL, delayed, cfg.Broker.ReconnectMinDelay, cfg.Broker.ReconnectMaxDelay)
+	if err != nil {
+		return fmt.Errorf("failed to connect to broker: %w", err)
+	}
+	a.closers = append(a.closers, conn.Close)
+
+	if delayed {
+		a.queue = rabbitMQ.NewQueueProps(conn, rabbitMQ.DelayedExchange, rabbitMQ.RoutingKey)
+	} else {
+		// наступившие сообщения публикуются прямо в рабочую очередь через обменник по умолчанию
+		redisScheduler := scheduler.NewRedis(rdb, rabbitMQ.NewQueueProps(conn, "", rabbitMQ.WorkQueue),
+			cfg.Scheduler.PollInterval, cfg.Scheduler.BatchSize)
+		a.queue = redisScheduler
+		a.runners = append(a.runners, redisScheduler.Run)
+	}
+
+	// Consumer получает наступившие уведомления из messageMainQueue по отдельному каналу,
+	// чтобы prefetch не влиял на публикацию
+	notifyWorker := worker.New(a.store, a.queue, senders, retry, limiter)
+	consumer := rabbitMQ.NewConsumer(conn, rabbitMQ.WorkQueue, cfg.Worker.Prefetch)
+	deadConsumer := rabbitMQ.NewConsumer(conn, rabbitMQ.DeadQueue, cfg.Worker.Prefetch)
+	a.runners = append(a.runners,
+		func(ctx context.Context) error {
+			if err := consumer.Run(ctx, cfg.Worker.Concurrency, notifyWorker.Handle); err != nil {
+				return fmt.Errorf("consumer is stopped: %w", err)
+			}
+			return nil
+		},
+		func(ctx context.Context) error {
+			if err := deadConsumer.Run(ctx, 1, notifyWorker.HandleDead); err != nil {
+				return fmt.Errorf("dead letter consumer is stopped: %w", err)
+			}
+			return nil
+		},
+	)
+	return nil
+}
+
+// Run запускает фоновые обработчики и блокируется до отмены ctx;
+// возвращает ошибку первого остановившегося с ошибкой обработчика
+func (a *App) Run(ctx context.Context) error {
+	errCh := make(chan error, len(a.runners))
+	for _, run := range a.runners {
+		go func() {
+			errCh <- run(ctx)
+		}()
+	}
+	for range a.runners {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler HTTP API сервиса
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Store хранилище уведомлений сервиса
+func (a *App) Store() Store {
+	return a.store
+}
+
+// Queue планировщик отложенных сообщений сервиса
+func (a *App) Queue() scheduler.Scheduler {
+	return a.queue
+}
+
+func (a *App) Close() {
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		a.closers[i]()
+	}
+}
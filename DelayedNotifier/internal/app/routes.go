@@ -0,0 +1,66 @@
+package app
+
+import (
+	"DelayedNotifier/internal/handlers"
+	"context"
+	"net/http"
+)
+
+// routes регистрирует HTTP API
+func (a *App) routes(ctx context.Context) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/notify", handlers.NotificationRequest(ctx, a.queue, a.store, a.callbacks))
+	mux.HandleFunc("/notify/{id}", handlers.NotificationRequest(ctx, a.queue, a.store, a.callbacks))
+	mux.HandleFunc("POST /notify/batch", func(w http.ResponseWriter, r *http.Request) {
+		handlers.CreateNotifications(ctx, a.queue, a.store, a.callbacks, w, r)
+	})
+	mux.HandleFunc("GET /notify/events", func(w http.ResponseWriter, r *http.Request) {
+		handlers.StreamEvents(ctx, a.events, a.store, w, r)
+	})
+	mux.HandleFunc("GET /notify/dead", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ListDeadNotifications(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("POST /notify/dead/replay", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ReplayDeadNotifications(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("POST /notify/{id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ReplayDeadNotifications(ctx, a.store, w, r)
+	})
+
+	mux.HandleFunc("GET /notify/{id}/occurrences", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ListOccurrences(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("POST /notify/{id}/pause", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PauseSeries(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("POST /notify/{id}/resume", func(w http.ResponseWriter, r *http.Request) {
+		handlers.ResumeSeries(ctx, a.queue, a.store, w, r)
+	})
+
+	mux.HandleFunc("POST /templates", func(w http.ResponseWriter, r *http.Request) {
+		handlers.CreateTemplate(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("GET /templates/{name}", func(w http.ResponseWriter, r *http.Request) {
+		handlers.GetTemplate(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("POST /templates/{name}/preview", func(w http.ResponseWriter, r *http.Request) {
+		handlers.PreviewTemplate(ctx, a.store, w, r)
+	})
+
+	mux.HandleFunc("PUT /preferences", func(w http.ResponseWriter, r *http.Request) {
+		handlers.SavePreferences(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("GET /preferences", func(w http.ResponseWriter, r *http.Request) {
+		handlers.GetPreferences(ctx, a.store, w, r)
+	})
+	mux.HandleFunc("DELETE /preferences", func(w http.ResponseWriter, r *http.Request) {
+		handlers.DeletePreferences(ctx, a.store, w, r)
+	})
+
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		handlers.Metrics(ctx, a.store, w, r)
+	})
+
+	return mux
+}
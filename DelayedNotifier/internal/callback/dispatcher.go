@@ -0,0 +1,201 @@
+package callback
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/sender"
+	"DelayedNotifier/internal/worker"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// errPermanent отказ, после которого событие не отправляется повторно
+	errPermanent = errors.New("callback is rejected")
+	// errNoSecret секрет подписи не задан: события остаются в очереди неотправленными
+	errNoSecret = errors.New("callback secret is not set")
+)
+
+// Dispatcher отправляет события смены статуса на callback_url уведомлений. Событие ставится
+// в очередь хранилищем вместе со сменой статуса, поэтому не теряется при сбоях; отправка
+// повторяется по своей RetryPolicy, независимо от повторов доставки уведомления.
+// Доставка at-least-once: повторы получатель отбрасывает по id события.
+// Тело подписывается так же, как вебхук канала webhook (sender.SignWebhook).
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	secret []byte
+	retry  worker.RetryPolicy
+
+	PollInterval time.Duration
+	BatchSize    int
+	Concurrency  int // число одновременных запросов
+}
+
+func NewDispatcher(store Store, cfg config.Callback) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// редиректы не выполняем: подпись привязана к исходному получателю
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		secret: []byte(cfg.Secret),
+		retry: worker.RetryPolicy{
+			BaseDelay:   cfg.RetryBaseDelay,
+			MaxDelay:    cfg.RetryMaxDelay,
+			MaxAttempts: cfg.MaxAttempts,
+		},
+		PollInterval: cfg.PollInterval,
+		BatchSize:    cfg.BatchSize,
+		Concurrency:  cfg.Concurrency,
+	}
+}
+
+// Run отправляет наступившие события каждые PollInterval до отмены ctx;
+// пока пачки заполнены целиком, следующая отправляется сразу
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			handled, err := d.Flush(ctx)
+			if err != nil {
+				log.Printf("Callback dispatcher: %s", err)
+			}
+			if err != nil || handled < d.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush отправляет одну пачку наступивших событий в Concurrency горутинах
+// и возвращает число событий в пачке
+func (d *Dispatcher) Flush(ctx context.Context) (int, error) {
+	// подпись пустым ключом может подделать кто угодно
+	if len(d.secret) == 0 {
+		return 0, errNoSecret
+	}
+	entries, err := d.store.DueCallbacks(ctx, time.Now(), d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	concurrency := d.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.dispatch(ctx, entry)
+			<-sem
+		}()
+	}
+	wg.Wait()
+	return len(entries), nil
+}
+
+// dispatch отправляет событие и снимает его с очереди или откладывает повтор
+func (d *Dispatcher) dispatch(ctx context.Context, entry models.CallbackEntry) {
+	event := entry.Event
+
+	notification, err := d.store.GetMessage(ctx, event.UUID)
+	if errors.Is(err, models.ErrNotFound) || (err == nil && notification.CallbackURL == "") {
+		// уведомление удалено: сообщать некуда
+		d.ack(ctx, entry)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load notification %s of callback %s: %s", event.UUID, event.ID, err)
+		return
+	}
+
+	err = d.post(ctx, notification.CallbackURL, event)
+	if err == nil {
+		d.ack(ctx, entry)
+		return
+	}
+
+	attempts := event.Attempts + 1
+	if errors.Is(err, errPermanent) || attempts >= d.retry.MaxAttempts {
+		log.Printf("Callback %s of notification %s is dropped after %d attempts: %s", event.ID, event.UUID, attempts, err)
+		d.ack(ctx, entry)
+		return
+	}
+
+	delay := d.retry.Delay(attempts)
+	log.Printf("Callback %s of notification %s failed, retry in %s: %s", event.ID, event.UUID, delay, err)
+	if err := d.store.RetryCallback(ctx, entry, time.Now().Add(delay)); err != nil {
+		log.Printf("Failed to retry callback %s: %s", event.ID, err)
+	}
+}
+
+// post отправляет подписанное событие; errPermanent – URL неверен или получатель отклонил событие
+func (d *Dispatcher) post(ctx context.Context, callbackURL string, event models.CallbackEvent) error {
+	target, err := url.Parse(callbackURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: invalid callback url %q", errPermanent, callbackURL)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sender.HeaderWebhookTimestamp, ts)
+	req.Header.Set(sender.HeaderWebhookSignature, sender.SignWebhook(d.secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("callback request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("callback responded with status %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+func (d *Dispatcher) ack(ctx context.Context, entry models.CallbackEntry) {
+	// неудачное подтверждение приведёт к повторной отправке, которую получатель отбросит по id
+	if err := d.store.AckCallback(ctx, entry); err != nil {
+		log.Printf("Failed to ack callback %s: %s", entry.Event.ID, err)
+	}
+}
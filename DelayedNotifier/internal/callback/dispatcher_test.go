@@ -0,0 +1,144 @@
+package callback
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/sender"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mock Store для тестирования
+type MockStore struct {
+	GetMessageFunc func(ctx context.Context, uuid string) (models.Notification, error)
+	entries        []models.CallbackEntry
+
+	mu      sync.Mutex
+	acked   []string
+	retried []models.CallbackEntry
+}
+
+func (m *MockStore) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	return m.GetMessageFunc(ctx, uuid)
+}
+
+func (m *MockStore) DueCallbacks(ctx context.Context, now time.Time, limit int) ([]models.CallbackEntry, error) {
+	return m.entries, nil
+}
+
+func (m *MockStore) AckCallback(ctx context.Context, entry models.CallbackEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acked = append(m.acked, entry.Event.ID)
+	return nil
+}
+
+func (m *MockStore) RetryCallback(ctx context.Context, entry models.CallbackEntry, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retried = append(m.retried, entry)
+	return nil
+}
+
+func TestDispatcher_Flush(t *testing.T) {
+	secret := "callback-secret"
+
+	tests := []struct {
+		name          string
+		statusCode    int
+		attempts      int
+		missing       bool
+		expectRequest bool
+		expectAck     bool
+		expectRetry   bool
+	}{
+		{name: "Delivered callback", statusCode: http.StatusOK, expectRequest: true, expectAck: true},
+		{name: "Server error is retried", statusCode: http.StatusBadGateway, expectRequest: true, expectRetry: true},
+		{name: "Rejected callback is dropped", statusCode: http.StatusBadRequest, expectRequest: true, expectAck: true},
+		{name: "Retry budget is exhausted", statusCode: http.StatusServiceUnavailable, attempts: 2, expectRequest: true, expectAck: true},
+		{name: "Deleted notification", missing: true, expectAck: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := false
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = true
+				body, _ := io.ReadAll(r.Body)
+				err := sender.VerifyWebhook([]byte(secret), r.Header.Get(sender.HeaderWebhookTimestamp),
+					r.Header.Get(sender.HeaderWebhookSignature), body, time.Minute, time.Now())
+				if err != nil {
+					t.Errorf("Invalid callback signature: %v", err)
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer receiver.Close()
+
+			event := models.CallbackEvent{ID: "event-1", UUID: "test-uuid", Status: models.StatusSent, At: time.Now(), Attempts: tt.attempts}
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					if tt.missing {
+						return models.Notification{}, models.ErrNotFound
+					}
+					n := models.Notification{UUID: uuid}
+					n.CallbackURL = receiver.URL
+					return n, nil
+				},
+				entries: []models.CallbackEntry{{Event: event, Key: "event-1"}},
+			}
+			d := NewDispatcher(store, config.Callback{
+				Secret:         secret,
+				Timeout:        time.Second,
+				BatchSize:      10,
+				RetryBaseDelay: time.Second,
+				RetryMaxDelay:  time.Minute,
+				MaxAttempts:    3,
+			})
+
+			handled, err := d.Flush(context.Background())
+			if err != nil || handled != 1 {
+				t.Fatalf("Expected 1 handled callback, got %d: %v", handled, err)
+			}
+			if requested != tt.expectRequest {
+				t.Errorf("Expected request: %v, got %v", tt.expectRequest, requested)
+			}
+			if (len(store.acked) == 1) != tt.expectAck {
+				t.Errorf("Expected ack: %v, got %v", tt.expectAck, store.acked)
+			}
+			if (len(store.retried) == 1) != tt.expectRetry {
+				t.Errorf("Expected retry: %v, got %v", tt.expectRetry, store.retried)
+			}
+		})
+	}
+}
+
+func TestDispatcher_FlushWithoutSecret(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected callback without secret, signature %q", r.Header.Get(sender.HeaderWebhookSignature))
+	}))
+	defer receiver.Close()
+
+	store := &MockStore{
+		GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+			n := models.Notification{UUID: uuid}
+			n.CallbackURL = receiver.URL
+			return n, nil
+		},
+		entries: []models.CallbackEntry{{Event: models.CallbackEvent{ID: "event-1", UUID: "test-uuid"}, Key: "event-1"}},
+	}
+	d := NewDispatcher(store, config.Callback{Timeout: time.Second, BatchSize: 10, MaxAttempts: 3})
+
+	if _, err := d.Flush(context.Background()); !errors.Is(err, errNoSecret) {
+		t.Fatalf("Expected errNoSecret, got %v", err)
+	}
+	// событие не отправлено и не снято с очереди
+	if len(store.acked) != 0 || len(store.retried) != 0 {
+		t.Errorf("Expected event to stay queued, acked %v, retried %v", store.acked, store.retried)
+	}
+}
@@ -0,0 +1,15 @@
+package callback
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"time"
+)
+
+// Store interface for Redis operations used by the dispatcher
+type Store interface {
+	GetMessage(ctx context.Context, uuid string) (models.Notification, error)
+	DueCallbacks(ctx context.Context, now time.Time, limit int) ([]models.CallbackEntry, error)
+	AckCallback(ctx context.Context, entry models.CallbackEntry) error
+	RetryCallback(ctx context.Context, entry models.CallbackEntry, until time.Time) error
+}
@@ -0,0 +1,162 @@
+package cron
+
+import (
+	"DelayedNotifier/internal/models"
+	"testing"
+	"time"
+)
+
+// TestSchedule_Next tests the next fire time of common expressions
+func TestSchedule_Next(t *testing.T) {
+	// суббота
+	after := time.Date(2025, 3, 1, 9, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Every 15 minutes",
+			expr:     "*/15 * * * *",
+			after:    after,
+			expected: time.Date(2025, 3, 1, 9, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "Weekdays at 10:00",
+			expr:     "0 10 * * MON-FRI",
+			after:    after,
+			expected: time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Strictly after the given time",
+			expr:     "0 10 * * *",
+			after:    time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Day of month or day of week",
+			expr:     "0 0 15 * SUN",
+			after:    after,
+			expected: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Sunday as 7",
+			expr:     "30 8 * * 7",
+			after:    after,
+			expected: time.Date(2025, 3, 2, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "List and range with step",
+			expr:     "0 9-17/4 * * *",
+			after:    after,
+			expected: time.Date(2025, 3, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Macro",
+			expr:     "@monthly",
+			after:    after,
+			expected: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Leap day",
+			expr:     "0 0 29 2 *",
+			after:    after,
+			expected: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Never",
+			expr:     "0 0 30 2 *",
+			after:    after,
+			expected: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got := schedule.Next(tt.after); !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSchedule_NextInTimezone tests wall clock times across a DST change
+func TestSchedule_NextInTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("tzdata is not available: %v", err)
+	}
+
+	schedule, err := Parse("0 10 * * *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// переход на летнее время 30 марта 2025
+	next := schedule.Next(time.Date(2025, 3, 29, 12, 0, 0, 0, loc))
+	expected := time.Date(2025, 3, 30, 10, 0, 0, 0, loc)
+	if !next.Equal(expected) {
+		t.Errorf("Expected %s, got %s", expected, next)
+	}
+	if _, offset := next.Zone(); offset != 2*60*60 {
+		t.Errorf("Expected CEST offset, got %d", offset)
+	}
+}
+
+// TestParse_Invalid tests rejection of malformed expressions
+func TestParse_Invalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range invalid {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected error for %q", expr)
+		}
+	}
+}
+
+// TestUpcoming tests occurrence limits of a series
+func TestUpcoming(t *testing.T) {
+	after := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	notification := models.Notification{Occurrences: 1}
+	notification.Cron = "0 * * * *"
+	notification.MaxOccurrences = 4
+
+	upcoming, err := Upcoming(notification, after, 10)
+	if err != nil {
+		t.Fatalf("Upcoming failed: %v", err)
+	}
+	if len(upcoming) != 3 {
+		t.Fatalf("Expected 3 occurrences, got %v", upcoming)
+	}
+	if !upcoming[2].Equal(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected last occurrence %s", upcoming[2])
+	}
+
+	notification.MaxOccurrences = 0
+	notification.Until = time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC)
+	upcoming, err = Upcoming(notification, after, 10)
+	if err != nil {
+		t.Fatalf("Upcoming failed: %v", err)
+	}
+	if len(upcoming) != 2 {
+		t.Errorf("Expected 2 occurrences until 11:00, got %v", upcoming)
+	}
+}
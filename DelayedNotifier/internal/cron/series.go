@@ -0,0 +1,71 @@
+package cron
+
+import (
+	"DelayedNotifier/internal/models"
+	"fmt"
+	"time"
+)
+
+// NextOccurrence возвращает следующее срабатывание повторяющегося уведомления после after
+// с учётом часового пояса, даты окончания и лимита срабатываний.
+// ok == false, если серия завершена.
+func NextOccurrence(notification models.Notification, after time.Time) (next time.Time, ok bool, err error) {
+	schedule, loc, err := load(notification)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return nextOccurrence(schedule, loc, notification, notification.Occurrences, after)
+}
+
+// Upcoming возвращает до count ближайших срабатываний серии после after
+func Upcoming(notification models.Notification, after time.Time, count int) ([]time.Time, error) {
+	schedule, loc, err := load(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := make([]time.Time, 0, count)
+	for occurrences := notification.Occurrences; len(upcoming) < count; occurrences++ {
+		next, ok, err := nextOccurrence(schedule, loc, notification, occurrences, after)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		upcoming = append(upcoming, next)
+		after = next
+	}
+	return upcoming, nil
+}
+
+func load(notification models.Notification) (*Schedule, *time.Location, error) {
+	schedule, err := Parse(notification.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc := time.UTC
+	if notification.Timezone != "" {
+		loc, err = time.LoadLocation(notification.Timezone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unknown timezone %q", notification.Timezone)
+		}
+	}
+	return schedule, loc, nil
+}
+
+func nextOccurrence(schedule *Schedule, loc *time.Location, notification models.Notification, occurrences int, after time.Time) (time.Time, bool, error) {
+	if notification.MaxOccurrences > 0 && occurrences >= notification.MaxOccurrences {
+		return time.Time{}, false, nil
+	}
+
+	next := schedule.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, false, nil
+	}
+	if !notification.Until.IsZero() && next.After(notification.Until) {
+		return time.Time{}, false, nil
+	}
+	return next, true, nil
+}
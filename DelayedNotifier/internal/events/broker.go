@@ -0,0 +1,109 @@
+package events
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// resubscribeDelay пауза перед повторной подпиской после обрыва
+	resubscribeDelay = time.Second
+	// subscriberBuffer сколько событий может ждать медленного подписчика
+	subscriberBuffer = 256
+)
+
+// Broker держит одну подписку на события смены статуса в хранилище (Redis Pub/Sub)
+// и раздаёт события всем подписчикам процесса, например SSE клиентам.
+// Подписчик, не успевающий читать, и все подписчики при обрыве подписки отключаются:
+// пропущенные события они получают заново из потока по последнему id.
+type Broker struct {
+	store Store
+
+	mu          sync.Mutex
+	subscribers map[chan models.StatusEvent]struct{}
+}
+
+func NewBroker(store Store) *Broker {
+	return &Broker{
+		store:       store,
+		subscribers: map[chan models.StatusEvent]struct{}{},
+	}
+}
+
+// Subscribe возвращает канал событий и функцию отписки. Канал закрывается брокером,
+// если подписчик отключён; тогда события после последнего полученного нужно читать из потока.
+func (b *Broker) Subscribe() (<-chan models.StatusEvent, func()) {
+	ch := make(chan models.StatusEvent, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drop(ch)
+	}
+}
+
+// Run раздаёт события до отмены ctx, подписываясь заново после обрыва
+func (b *Broker) Run(ctx context.Context) error {
+	for {
+		err := b.run(ctx)
+		b.dropAll()
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("Status events subscription is interrupted, resubscribe: %v", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+func (b *Broker) run(ctx context.Context) error {
+	events, err := b.store.SubscribeEvents(ctx)
+	if err != nil {
+		return err
+	}
+	for event := range events {
+		b.publish(event)
+	}
+	return errors.New("events channel is closed")
+}
+
+func (b *Broker) publish(event models.StatusEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Status events subscriber is too slow, disconnect it")
+			b.drop(ch)
+		}
+	}
+}
+
+func (b *Broker) dropAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		b.drop(ch)
+	}
+}
+
+// drop закрывает канал подписчика; вызывается под b.mu
+func (b *Broker) drop(ch chan models.StatusEvent) {
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// MockStore отдаёт события из канала events
+type MockStore struct {
+	events chan models.StatusEvent
+}
+
+func (m *MockStore) SubscribeEvents(ctx context.Context) (<-chan models.StatusEvent, error) {
+	return m.events, nil
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ID
+		err   error
+	}{
+		{value: "1700000000000-0", want: ID{Ms: 1700000000000}},
+		{value: "1700000000000-12", want: ID{Ms: 1700000000000, Seq: 12}},
+		{value: "1700000000000", err: ErrInvalidID},
+		{value: "abc-1", err: ErrInvalidID},
+		{value: "1-", err: ErrInvalidID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseID(tt.value)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Fatalf("Expected %v, %v, got %v, %v", tt.want, tt.err, got, err)
+			}
+			if err == nil && got.String() != tt.value {
+				t.Errorf("Expected %s, got %s", tt.value, got)
+			}
+		})
+	}
+
+	id := ID{Ms: 10, Seq: 1}
+	if next := id.Next(10); next != (ID{Ms: 10, Seq: 2}) || !next.After(id) {
+		t.Errorf("Unexpected next id in the same millisecond: %v", next)
+	}
+	// часы отстали – id всё равно растёт
+	if next := id.Next(9); !next.After(id) {
+		t.Errorf("Expected next id to be after %v, got %v", id, next)
+	}
+	if next := id.Next(11); next != (ID{Ms: 11}) {
+		t.Errorf("Unexpected next id: %v", next)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	store := &MockStore{events: make(chan models.StatusEvent)}
+	broker := NewBroker(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		broker.Run(ctx)
+		close(done)
+	}()
+
+	fast, unsubscribe := broker.Subscribe()
+	defer unsubscribe()
+	slow, _ := broker.Subscribe()
+
+	for i := range subscriberBuffer + 1 {
+		store.events <- models.StatusEvent{ID: ID{Ms: uint64(i + 1)}.String()}
+		<-fast
+	}
+	// медленный подписчик отключён после переполнения буфера, его канал закрыт
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, received)
+	}
+
+	// остановка брокера закрывает каналы оставшихся подписчиков
+	cancel()
+	close(store.events)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Broker is not stopped")
+	}
+	if _, ok := <-fast; ok {
+		t.Error("Expected subscriber channel to be closed")
+	}
+}
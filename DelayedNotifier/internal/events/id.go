@@ -0,0 +1,53 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidID id события не в формате потока Redis "<мс>-<номер>"
+var ErrInvalidID = errors.New("invalid event id")
+
+// ID идентификатор события в потоке: время добавления (мс) и номер внутри миллисекунды,
+// как ID записи Redis stream. Идентификаторы событий растут в порядке их появления.
+type ID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+func ParseID(value string) (ID, error) {
+	ms, seq, ok := strings.Cut(value, "-")
+	if !ok {
+		return ID{}, ErrInvalidID
+	}
+	var id ID
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return ID{}, ErrInvalidID
+	}
+	if id.Seq, err = strconv.ParseUint(seq, 10, 64); err != nil {
+		return ID{}, ErrInvalidID
+	}
+	return id, nil
+}
+
+func (id ID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// After сообщает, появилось ли событие id позже other
+func (id ID) After(other ID) bool {
+	if id.Ms != other.Ms {
+		return id.Ms > other.Ms
+	}
+	return id.Seq > other.Seq
+}
+
+// Next идентификатор следующего события, добавленного в момент ms
+func (id ID) Next(ms uint64) ID {
+	if ms > id.Ms {
+		return ID{Ms: ms}
+	}
+	return ID{Ms: id.Ms, Seq: id.Seq + 1}
+}
@@ -0,0 +1,12 @@
+package events
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+)
+
+// Store interface for Redis operations used by the broker
+type Store interface {
+	// SubscribeEvents подписывается на новые события; канал закрывается при отмене ctx или обрыве подписки
+	SubscribeEvents(ctx context.Context) (<-chan models.StatusEvent, error)
+}
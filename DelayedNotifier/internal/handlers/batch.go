@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// maxBatchSize предел числа уведомлений в одном POST /notify/batch
+const maxBatchSize = 1000
+
+// Результаты создания уведомления из пачки
+const (
+	batchResultCreated   = "created"
+	batchResultDuplicate = "duplicate"
+	batchResultInvalid   = "invalid"
+	batchResultError     = "error"
+)
+
+type batchRequest struct {
+	Notifications []json.RawMessage `json:"notifications"`
+}
+
+type batchResult struct {
+	Index  int    `json:"index"`
+	UUID   string `json:"uuid,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Created int           `json:"created"`
+	Results []batchResult `json:"results"`
+}
+
+// CreateNotifications POST /notify/batch – создаёт до maxBatchSize уведомлений за запрос.
+// Все уведомления проверяются до записи, корректные сохраняются одним pipeline запросом
+// и публикуются outbox relay пачками. Каждое уведомление создаётся независимо: ответ
+// содержит результат для каждого (created/duplicate/invalid/error) в порядке запроса.
+// С Idempotency-Key повтор возвращает первый ответ, поэтому уведомления с результатом
+// error нужно отправить повторно с новым ключом. callbacks – как в CreateNotification.
+func CreateNotifications(ctx context.Context, qp QueueProducer, rdb RedisStore, callbacks bool, w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		log.Printf("Failed to read body: %s", err)
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	withIdempotency(ctx, rdb, w, r, data, func(w http.ResponseWriter) {
+		createBatch(ctx, qp, rdb, callbacks, w, data)
+	})
+}
+
+func createBatch(ctx context.Context, qp QueueProducer, rdb RedisStore, callbacks bool, w http.ResponseWriter, data []byte) {
+	var req batchRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Notifications) == 0 {
+		http.Error(w, "notifications must not be empty", http.StatusBadRequest)
+		return
+	}
+	if len(req.Notifications) > maxBatchSize {
+		http.Error(w, fmt.Sprintf("at most %d notifications are allowed in a batch", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(req.Notifications))
+	var messages, fanouts []models.Notification
+	var messageIndexes, fanoutIndexes []int
+
+	templates := &templateCache{rdb: rdb, templates: map[templateKey]models.Template{}}
+	now := time.Now()
+	for i, raw := range req.Notifications {
+		results[i].Index = i
+
+		var notification models.Notification
+		if err := json.Unmarshal(raw, &notification); err != nil {
+			results[i].Result, results[i].Error = batchResultInvalid, "Failed to unmarshal JSON: "+err.Error()
+			continue
+		}
+		err := prepareNotification(ctx, templates, callbacks, qp.MaxDelay(), &notification, now)
+		var invalid invalidError
+		if errors.As(err, &invalid) {
+			results[i].UUID = notification.UUID
+			results[i].Result, results[i].Error = batchResultInvalid, err.Error()
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to prepare notification: %s", err)
+			results[i].Result, results[i].Error = batchResultError, err.Error()
+			continue
+		}
+
+		results[i].UUID = notification.UUID
+		if len(notification.Recipients) > 0 {
+			fanouts = append(fanouts, notification)
+			fanoutIndexes = append(fanoutIndexes, i)
+			continue
+		}
+		messages = append(messages, notification)
+		messageIndexes = append(messageIndexes, i)
+	}
+
+	if len(messages) > 0 {
+		saved, err := rdb.SaveMessages(ctx, messages)
+		if err != nil {
+			log.Printf("Failed to save notifications: %s", err)
+			http.Error(w, "Failed to save notifications", http.StatusInternalServerError)
+			return
+		}
+		for j, err := range saved {
+			results[messageIndexes[j]].setSaved(err)
+		}
+	}
+	// рассылка сохраняется вместе с доставками получателям отдельной операцией
+	for j, fanout := range fanouts {
+		err := rdb.SaveFanout(ctx, fanout, fanOut(&fanout))
+		results[fanoutIndexes[j]].setSaved(err)
+	}
+
+	response := batchResponse{Results: results}
+	for _, result := range results {
+		if result.Result == batchResultCreated {
+			response.Created++
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// setSaved записывает результат сохранения уведомления
+func (r *batchResult) setSaved(err error) {
+	switch {
+	case err == nil:
+		r.Result = batchResultCreated
+	case errors.Is(err, models.ErrAlreadyExists):
+		r.Result = batchResultDuplicate
+	default:
+		log.Printf("Failed to save notification %s: %s", r.UUID, err)
+		r.Result, r.Error = batchResultError, err.Error()
+	}
+}
+
+type templateKey struct {
+	name    string
+	version int
+}
+
+// templateCache запоминает загруженные шаблоны на время одного запроса:
+// уведомления пачки обычно используют один и тот же шаблон
+type templateCache struct {
+	rdb       templateStore
+	templates map[templateKey]models.Template
+}
+
+func (c *templateCache) GetTemplate(ctx context.Context, name string, version int) (models.Template, error) {
+	key := templateKey{name: name, version: version}
+	if template, ok := c.templates[key]; ok {
+		return template, nil
+	}
+	template, err := c.rdb.GetTemplate(ctx, name, version)
+	if err != nil {
+		return models.Template{}, err
+	}
+	c.templates[key] = template
+	return template, nil
+}
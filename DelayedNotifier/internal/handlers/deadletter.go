@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const defaultDeadListLimit = 100
+
+// Результаты повторной отправки dead-letter уведомления
+const (
+	replayResultReplayed = "replayed"
+	replayResultNotFound = "not_found"
+	replayResultError    = "error"
+)
+
+type replayRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type replayResult struct {
+	UUID   string `json:"uuid"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ListDeadNotifications GET /notify/dead?limit=N – окончательно неудачные уведомления с историей попыток
+func ListDeadNotifications(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	limit := defaultDeadListLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	dead, err := rdb.ListDead(ctx, limit)
+	if err != nil {
+		log.Printf("Failed to list dead notifications: %s", err)
+		http.Error(w, "Failed to list dead notifications", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dead)
+}
+
+// ReplayDeadNotifications POST /notify/{id}/replay – повторная отправка одного уведомления,
+// POST /notify/dead/replay – списка {"ids": [...]} или всех dead-letter уведомлений при пустом теле
+func ReplayDeadNotifications(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	if uuid := r.PathValue("id"); uuid != "" {
+		result := replayDead(ctx, rdb, uuid)
+		switch result.Result {
+		case replayResultNotFound:
+			http.Error(w, "Dead notification is not found", http.StatusNotFound)
+		case replayResultError:
+			http.Error(w, "Failed to replay notification: "+result.Error, http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusAccepted, result)
+		}
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var req replayRequest
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req); err != nil {
+			http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if len(req.IDs) == 0 {
+		dead, err := rdb.ListDead(ctx, 0)
+		if err != nil {
+			log.Printf("Failed to list dead notifications: %s", err)
+			http.Error(w, "Failed to list dead notifications", http.StatusInternalServerError)
+			return
+		}
+		for _, d := range dead {
+			req.IDs = append(req.IDs, d.UUID)
+		}
+	}
+
+	results := make([]replayResult, 0, len(req.IDs))
+	for _, uuid := range req.IDs {
+		results = append(results, replayDead(ctx, rdb, uuid))
+	}
+
+	writeJSON(w, http.StatusOK, results)
+}
+
+// replayDead возвращает уведомление из dead-letter в ожидание на немедленную доставку:
+// хранилище в той же операции ставит его в outbox, в очередь его публикует outbox relay
+func replayDead(ctx context.Context, rdb RedisStore, uuid string) replayResult {
+	_, err := rdb.ReviveDead(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		return replayResult{UUID: uuid, Result: replayResultNotFound}
+	}
+	if err != nil {
+		log.Printf("Failed to revive dead notification %s: %s", uuid, err)
+		return replayResult{UUID: uuid, Result: replayResultError, Error: err.Error()}
+	}
+
+	return replayResult{UUID: uuid, Result: replayResultReplayed}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to marshal response: %s", err)
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(jsonData); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/events"
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// eventsReplayBatch сколько событий читается из потока за раз при возобновлении
+	eventsReplayBatch = 500
+	// eventsHeartbeat интервал комментариев, не дающих прокси закрыть простаивающее соединение
+	eventsHeartbeat = 15 * time.Second
+)
+
+// eventFilter фильтр GET /notify/events: id – уведомление или рассылка, события которой
+// включают события её получателей; пустые поля не фильтруют
+type eventFilter struct {
+	id     string
+	tag    string
+	tenant string
+}
+
+func (f eventFilter) match(event models.StatusEvent) bool {
+	if f.id != "" && event.UUID != f.id && event.ParentUUID != f.id {
+		return false
+	}
+	if f.tag != "" && !slices.Contains(event.Tags, f.tag) {
+		return false
+	}
+	return f.tenant == "" || event.Tenant == f.tenant
+}
+
+// StreamEvents GET /notify/events?id=&tag=&tenant= – смены статусов уведомлений
+// в формате Server-Sent Events. С заголовком Last-Event-ID сначала отдаются события,
+// пропущенные после него, из потока в хранилище. Поток закрывается, если клиент не успевает
+// читать или прервалась подписка на хранилище: клиент переподключается с Last-Event-ID.
+func StreamEvents(ctx context.Context, source EventSource, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := eventFilter{id: query.Get("id"), tag: query.Get("tag"), tenant: query.Get("tenant")}
+
+	var last events.ID
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID != "" {
+		parsed, err := events.ParseID(lastEventID)
+		if err != nil {
+			http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		last = parsed
+	}
+
+	// подписка до чтения потока: события, появившиеся во время возобновления, не теряются,
+	// а уже отданные отбрасываются по id
+	live, unsubscribe := source.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Printf("Failed to stream events: %s", err)
+		return
+	}
+
+	write := func(event models.StatusEvent) bool {
+		id, err := events.ParseID(event.ID)
+		if err != nil || !id.After(last) {
+			return true
+		}
+		last = id
+		if !filter.match(event) {
+			return true
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: status\ndata: %s\n\n", event.ID, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if lastEventID != "" {
+		for {
+			missed, err := rdb.EventsAfter(ctx, last.String(), eventsReplayBatch)
+			if err != nil {
+				log.Printf("Failed to replay events after %s: %s", last, err)
+				return
+			}
+			for _, event := range missed {
+				if !write(event) {
+					return
+				}
+			}
+			if len(missed) < eventsReplayBatch {
+				break
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case event, ok := <-live:
+			if !ok || !write(event) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// maxRecipients предел числа получателей одной рассылки
+const maxRecipients = 100
+
+// recipientStatus состояние доставки одному получателю рассылки
+type recipientStatus struct {
+	models.Recipient
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// fanoutResponse рассылка со сводным статусом и состоянием доставок получателям
+type fanoutResponse struct {
+	models.Notification
+	Recipients []recipientStatus `json:"recipients"`
+}
+
+// validateRecipients проверяет получателей рассылки; канал по умолчанию – лог
+func validateRecipients(notification *models.Notification) error {
+	if len(notification.Recipients) == 0 {
+		return nil
+	}
+	if notification.Channel != "" || notification.Recipient != "" {
+		return errors.New("recipients and channel/recipient are mutually exclusive")
+	}
+	if len(notification.Recipients) > maxRecipients {
+		return fmt.Errorf("at most %d recipients are allowed", maxRecipients)
+	}
+
+	seen := make(map[models.Recipient]bool, len(notification.Recipients))
+	for i := range notification.Recipients {
+		recipient := &notification.Recipients[i]
+		recipient.UUID = ""
+		if recipient.Channel == "" {
+			recipient.Channel = models.ChannelLog
+		}
+		if recipient.Address == "" && recipient.Channel != models.ChannelLog {
+			return fmt.Errorf("recipient %d: address is required for channel %q", i, recipient.Channel)
+		}
+		if seen[*recipient] {
+			return fmt.Errorf("recipient %d: duplicate of %s %q", i, recipient.Channel, recipient.Address)
+		}
+		seen[*recipient] = true
+	}
+	return nil
+}
+
+// fanOut делит рассылку на уведомления получателям; UUID доставок записываются в parent.Recipients
+func fanOut(parent *models.Notification) []models.Notification {
+	children := make([]models.Notification, 0, len(parent.Recipients))
+	for i := range parent.Recipients {
+		recipient := &parent.Recipients[i]
+		recipient.UUID = uuid.NewString()
+
+		child := *parent
+		child.UUID = recipient.UUID
+		child.ParentUUID = parent.UUID
+		child.Channel = recipient.Channel
+		child.Recipient = recipient.Address
+		child.Recipients = nil
+		children = append(children, child)
+	}
+	return children
+}
+
+// createFanout сохраняет рассылку вместе с уведомлениями получателям, которые публикует outbox relay.
+// Рассылка поддерживает только GET и DELETE: PATCH, replay и операции серий
+// применяются к уведомлениям отдельных получателей.
+func createFanout(ctx context.Context, rdb RedisStore, w http.ResponseWriter, notification models.Notification) {
+	children := fanOut(&notification)
+
+	err := rdb.SaveFanout(ctx, notification, children)
+	if errors.Is(err, models.ErrAlreadyExists) {
+		http.Error(w, "Notification "+notification.UUID+" already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save fan-out notification: %s", err)
+		http.Error(w, "Failed to save notification", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/notify/"+notification.UUID)
+	writeJSON(w, http.StatusCreated, notification)
+}
+
+// loadFanout собирает рассылку со статусами доставок; удалённые доставки пропускаются
+func loadFanout(ctx context.Context, rdb RedisStore, parent models.Notification) (fanoutResponse, error) {
+	response := fanoutResponse{Notification: parent, Recipients: make([]recipientStatus, 0, len(parent.Recipients))}
+	statuses := make([]string, 0, len(parent.Recipients))
+	for _, recipient := range parent.Recipients {
+		child, err := rdb.GetMessage(ctx, recipient.UUID)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fanoutResponse{}, err
+		}
+
+		response.Recipients = append(response.Recipients, recipientStatus{
+			Recipient: recipient,
+			Status:    child.Status,
+			Attempts:  child.Attempts,
+			LastError: child.LastError,
+		})
+		statuses = append(statuses, child.Status)
+	}
+	response.Status = models.AggregateStatus(statuses)
+	return response, nil
+}
+
+// getFanout GET /notify/{id} для рассылки: сводный статус и состояние каждого получателя
+func getFanout(ctx context.Context, rdb RedisStore, w http.ResponseWriter, uuid string) {
+	parent, err := rdb.GetFanout(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get fan-out notification: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return
+	}
+
+	response, err := loadFanout(ctx, rdb, parent)
+	if err != nil {
+		log.Printf("Failed to get fan-out recipients: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// cancelFanout DELETE /notify/{id} для рассылки: отменяет доставки, которые ещё не начались.
+// 409 – если отменять уже нечего
+func cancelFanout(ctx context.Context, qp QueueProducer, rdb RedisStore, w http.ResponseWriter, uuid string) {
+	parent, err := rdb.GetFanout(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get fan-out notification: %s", err)
+		http.Error(w, "Failed to cancel notification", http.StatusInternalServerError)
+		return
+	}
+
+	cancelled := 0
+	for _, recipient := range parent.Recipients {
+		err := rdb.CancelMessage(ctx, recipient.UUID)
+		if errors.Is(err, models.ErrNotFound) || errors.Is(err, models.ErrStatusConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to cancel notification %s: %s", recipient.UUID, err)
+			http.Error(w, "Failed to cancel notification", http.StatusInternalServerError)
+			return
+		}
+		cancelled++
+		if err := qp.CancelMessageDelay(recipient.UUID); err != nil {
+			log.Printf("Failed to cancel delayed message: %s", err)
+		}
+	}
+	if cancelled == 0 {
+		http.Error(w, "Notification can not be cancelled: no pending recipients", http.StatusConflict)
+		return
+	}
+
+	response, err := loadFanout(ctx, rdb, parent)
+	if err != nil {
+		log.Printf("Failed to get fan-out recipients: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
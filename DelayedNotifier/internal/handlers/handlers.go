@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"DelayedNotifier/internal/models"
-	"DelayedNotifier/internal/rabbitMQ"
-	"DelayedNotifier/internal/redisdb"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 	//amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -20,8 +25,14 @@ const (
 	qname = ""
 )
 
-// Post request to create notification
-func CreateNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+// Post request to create notification.
+// Уведомление с recipients – рассылка: каждому получателю создаётся отдельное уведомление (см. fanout.go).
+// Без uuid в теле идентификатор генерирует сервер, занятый uuid отклоняется с 409;
+// повтор запроса с тем же Idempotency-Key возвращает первый ответ (см. idempotency.go).
+// Запрос только сохраняет уведомление: в очередь его публикует outbox relay,
+// поэтому qp здесь только ограничивает задержку. callbacks – включена ли отправка событий на callback_url
+// (задан config.Callback.Secret); если нет, уведомление с callback_url отклоняется.
+func CreateNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, callbacks bool, w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Printf("Failed to read body: %s", err)
@@ -29,68 +40,166 @@ func CreateNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, w
 		return
 	}
 
+	withIdempotency(ctx, rdb, w, r, data, func(w http.ResponseWriter) {
+		createNotification(ctx, qp, rdb, callbacks, w, data)
+	})
+}
+
+func createNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, callbacks bool, w http.ResponseWriter, data []byte) {
 	var notification models.Notification
-	err = json.Unmarshal(data, &notification)
+	err := json.Unmarshal(data, &notification)
 	if err != nil {
 		log.Printf("Failed to unmarshal JSON: %s", err)
 		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	// Добавление Id параметра в очередь
-	err = qp.SendMessage(notification)
+	err = prepareNotification(ctx, rdb, callbacks, qp.MaxDelay(), &notification, time.Now())
+	var invalid invalidError
+	if errors.As(err, &invalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		log.Printf("Failed to send notification: %s", err)
-		http.Error(w, "Failed to send notification: "+err.Error(), http.StatusInternalServerError)
+		log.Printf("Failed to prepare notification: %s", err)
+		http.Error(w, "Failed to save notification", http.StatusInternalServerError)
 		return
 	}
 
-	// Теперь сохранение в базу данных набора данных
-	notification.Status = models.StatusPending
+	if len(notification.Recipients) > 0 {
+		createFanout(ctx, rdb, w, notification)
+		return
+	}
+
+	// Запись и постановка в outbox выполняются одной операцией: уведомление
+	// не потеряется при сбое между сохранением и публикацией в очередь
 	err = rdb.SaveMessage(ctx, notification)
+	if errors.Is(err, models.ErrAlreadyExists) {
+		http.Error(w, "Notification "+notification.UUID+" already exists", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		log.Printf("Failed to save notification: %s", err)
 		http.Error(w, "Failed to save notification", http.StatusInternalServerError)
 		return
 	}
 
-	// TODO: to add some ResponseWriter parameters
-	w.WriteHeader(http.StatusCreated)
-	_, err = w.Write([]byte("Message is created"))
+	w.Header().Set("Location", "/notify/"+notification.UUID)
+	writeJSON(w, http.StatusCreated, notification)
+}
+
+// invalidError ошибка проверки нового уведомления: текст возвращается клиенту с кодом 400
+type invalidError string
+
+func (e invalidError) Error() string {
+	return string(e)
+}
+
+// prepareNotification проверяет новое уведомление и заполняет поля, которые назначает сервер:
+// UUID, статус, время срабатывания и версию шаблона. Ошибка проверки – invalidError,
+// остальные ошибки – сбой хранилища шаблонов. maxDelay – потолок задержки планировщика
+func prepareNotification(ctx context.Context, rdb templateStore, callbacks bool, maxDelay time.Duration, notification *models.Notification, now time.Time) error {
+	// uuid – ключ записи в хранилище и сегмент пути /notify/{id}: произвольная строка
+	// совпала бы со служебными ключами (notify:outbox) или маршрутами (/notify/events)
+	if notification.UUID != "" && !validUUID(notification.UUID) {
+		return invalidError("uuid must be a UUID in the 8-4-4-4-12 form")
+	}
+	if notification.MaxAttempts < 0 {
+		return invalidError("max_attempts must not be negative")
+	}
+	for _, tag := range notification.Tags {
+		if tag == "" || strings.Contains(tag, ",") {
+			return invalidError("tags must be non-empty and must not contain commas")
+		}
+	}
+	if notification.Priority != "" && !slices.Contains(models.Priorities, notification.Priority) {
+		return invalidError("Unknown priority " + notification.Priority)
+	}
+	if notification.CallbackURL != "" && !callbacks {
+		// без секрета событие нечем подписать
+		return invalidError("callback_url is not supported: callbacks are disabled")
+	}
+	if notification.CallbackURL != "" && !validCallbackURL(notification.CallbackURL) {
+		return invalidError("callback_url must be an absolute http or https URL")
+	}
+	if err := validateRecipients(notification); err != nil {
+		return invalidError("Invalid recipients: " + err.Error())
+	}
+	err := resolveTemplate(ctx, rdb, notification)
+	if errors.Is(err, models.ErrNotFound) {
+		return invalidError("Template " + notification.Template + " is not found")
+	}
+	if errors.Is(err, errInvalidTemplate) {
+		return invalidError(err.Error())
+	}
 	if err != nil {
-		log.Printf("Failed to write response: %s", err)
-		http.Error(w, "Failed to write response: "+err.Error(), http.StatusInternalServerError)
-		return
+		return err
+	}
+	if err := resolveSchedule(notification, now, maxDelay); err != nil {
+		return invalidError("Invalid schedule: " + err.Error())
 	}
-	return
+	if notification.UUID == "" {
+		notification.UUID = uuid.NewString()
+	}
+	notification.ParentUUID = ""
+	notification.Status = models.StatusPending
+	return nil
 }
 
+func validUUID(value string) bool {
+	_, err := uuid.Parse(value)
+	// uuid.Parse принимает и формы urn:uuid:..., {...} и без дефисов
+	return err == nil && len(value) == 36
+}
+
+func validCallbackURL(value string) bool {
+	target, err := url.Parse(value)
+	return err == nil && (target.Scheme == "http" || target.Scheme == "https") && target.Host != ""
+}
+
+// notificationResponse полная запись уведомления для GET /notify/{id}
+type notificationResponse struct {
+	models.Notification
+	// FiredAt фактическое время последнего срабатывания (захвата worker-ом)
+	FiredAt time.Time             `json:"fired_at,omitzero"`
+	History []models.StatusChange `json:"history"`
+}
+
+// GetNotificationStatus GET /notify/{id} – уведомление целиком с историей смен статуса,
+// для рассылки – сводный статус и статусы получателей; 404 – неизвестный id
 func GetNotificationStatus(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
 	uuid := r.PathValue("id")
 
-	status, err := rdb.GetStatus(ctx, uuid)
+	notification, err := rdb.GetMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		getFanout(ctx, rdb, w, uuid)
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Failed to get notification: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	response := map[string]string{"status": status}
-	jsonData, err := json.Marshal(response)
+	history, err := rdb.GetHistory(ctx, uuid)
 	if err != nil {
-		log.Printf("Failed to marshal response: %s", err)
+		log.Printf("Failed to get status history: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return
 	}
 
-	_, err = w.Write(jsonData)
-	if err != nil {
-		log.Printf("Failed to write response: %s", err)
+	response := notificationResponse{Notification: notification, History: history}
+	for _, change := range history {
+		if change.Status == models.StatusProcessing {
+			response.FiredAt = change.At
+		}
 	}
 
-	return
+	writeJSON(w, http.StatusOK, response)
 }
 
-func DeleteNotification(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+// DeleteNotification отменяет запланированное уведомление или все ожидающие доставки рассылки:
+// 404 – неизвестный id, 409 – уведомление уже отправляется или находится в конечном статусе
+func DeleteNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
 	uuid := r.PathValue("id")
 
 	if uuid == "" {
@@ -98,30 +207,50 @@ func DeleteNotification(ctx context.Context, rdb RedisStore, w http.ResponseWrit
 		return
 	}
 
-	w.WriteHeader(http.StatusAccepted)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message":"Notification deletion in progress"}`))
+	err := rdb.CancelMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		cancelFanout(ctx, qp, rdb, w, uuid)
+		return
+	}
+	if errors.Is(err, models.ErrStatusConflict) {
+		http.Error(w, "Notification can not be cancelled: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to cancel notification: %s", err)
+		http.Error(w, "Failed to cancel notification", http.StatusInternalServerError)
+		return
+	}
 
-	go func(ctx context.Context) {
-		err := rdb.DeleteMessage(ctx, uuid)
-		if err != nil {
-			log.Printf("Failed to delete message: %s", err)
-		} else {
-			log.Printf("Notification is deleted")
-		}
-	}(ctx)
+	if err := qp.CancelMessageDelay(uuid); err != nil {
+		log.Printf("Failed to cancel delayed message: %s", err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"uuid": uuid, "status": models.StatusCancelled})
 }
 
 // message string, timestamp int64
-func NotificationRequest(ctx context.Context, conn *rabbitMQ.QueueProps, rdb *redisdb.RedisConnection) func(w http.ResponseWriter, r *http.Request) {
+func NotificationRequest(ctx context.Context, conn QueueProducer, rdb RedisStore, callbacks bool) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPost:
-			CreateNotification(ctx, conn, rdb, w, r)
+			CreateNotification(ctx, conn, rdb, callbacks, w, r)
 		case http.MethodGet:
+			// /notify без id – список уведомлений
+			if r.PathValue("id") == "" {
+				ListNotifications(ctx, rdb, w, r)
+				return
+			}
 			GetNotificationStatus(ctx, rdb, w, r)
 		case http.MethodDelete:
-			DeleteNotification(ctx, rdb, w, r)
+			DeleteNotification(ctx, conn, rdb, w, r)
+		case http.MethodPatch:
+			// изменить можно только одно уведомление, не коллекцию
+			if r.PathValue("id") == "" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			EditNotification(ctx, conn, rdb, w, r)
 		default:
 			w.WriteHeader(http.StatusMethodNotAllowed)
 		}
@@ -6,17 +6,22 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 // Mock QueueProps для тестирования
 type MockQueueProps struct {
-	SendMessageFunc func(notification models.Notification) error
+	SendMessageFunc        func(notification models.Notification) error
+	CancelMessageDelayFunc func(uuid string) error
+	MaxDelayFunc           func() time.Duration
 }
 
 func (m *MockQueueProps) SendMessage(notification models.Notification) error {
@@ -26,11 +31,45 @@ func (m *MockQueueProps) SendMessage(notification models.Notification) error {
 	return nil
 }
 
+func (m *MockQueueProps) CancelMessageDelay(uuid string) error {
+	if m.CancelMessageDelayFunc != nil {
+		return m.CancelMessageDelayFunc(uuid)
+	}
+	return nil
+}
+
+// MaxDelay по умолчанию – потолок delayed exchange, как у планировщика rabbitmq
+func (m *MockQueueProps) MaxDelay() time.Duration {
+	if m.MaxDelayFunc != nil {
+		return m.MaxDelayFunc()
+	}
+	return models.MaxDelay
+}
+
 // Mock RedisConnection для тестирования
 type MockRedisConnection struct {
 	SaveMessageFunc   func(ctx context.Context, notif models.Notification) error
-	GetStatusFunc     func(ctx context.Context, uuid string) (string, error)
-	DeleteMessageFunc func(ctx context.Context, uuid string) error
+	GetMessageFunc    func(ctx context.Context, uuid string) (models.Notification, error)
+	GetHistoryFunc    func(ctx context.Context, uuid string) ([]models.StatusChange, error)
+	ListMessagesFunc  func(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error)
+	CancelMessageFunc func(ctx context.Context, uuid string) error
+	SaveStatusFunc    func(ctx context.Context, uuid string, status string) error
+	ListDeadFunc      func(ctx context.Context, limit int) ([]models.DeadLetter, error)
+	ReviveDeadFunc    func(ctx context.Context, uuid string) (models.Notification, error)
+	MarkDeadFunc      func(ctx context.Context, uuid string, at time.Time) error
+	TransitionFunc    func(ctx context.Context, uuid string, to string, from ...string) (string, error)
+	EditMessageFunc   func(ctx context.Context, notif models.Notification, version int, newVersion int) error
+	ResumeSeriesFunc  func(ctx context.Context, uuid string, version int, fireAt time.Time) error
+	SaveTemplateFunc  func(ctx context.Context, t models.Template) (models.Template, error)
+	GetTemplateFunc   func(ctx context.Context, name string, version int) (models.Template, error)
+	SaveFanoutFunc    func(ctx context.Context, parent models.Notification, children []models.Notification) error
+	GetFanoutFunc     func(ctx context.Context, uuid string) (models.Notification, error)
+	BacklogFunc       func(ctx context.Context, now time.Time) (map[string]int, error)
+	EventsAfterFunc   func(ctx context.Context, id string, limit int) ([]models.StatusEvent, error)
+	// ключи идемпотентности и настройки получателей хранятся в памяти мока
+	idempotency    map[string]models.IdempotencyRecord
+	idempotencyTTL map[string]time.Duration
+	preferences    map[string]models.Preferences
 }
 
 func (m *MockRedisConnection) SaveMessage(ctx context.Context, notif models.Notification) error {
@@ -40,17 +79,181 @@ func (m *MockRedisConnection) SaveMessage(ctx context.Context, notif models.Noti
 	return nil
 }
 
-func (m *MockRedisConnection) GetStatus(ctx context.Context, uuid string) (string, error) {
-	if m.GetStatusFunc != nil {
-		return m.GetStatusFunc(ctx, uuid)
+// SaveMessages сохраняет пачку через SaveMessage, как pipeline – каждое уведомление независимо
+func (m *MockRedisConnection) SaveMessages(ctx context.Context, notifs []models.Notification) ([]error, error) {
+	results := make([]error, len(notifs))
+	for i, notif := range notifs {
+		results[i] = m.SaveMessage(ctx, notif)
+	}
+	return results, nil
+}
+
+func (m *MockRedisConnection) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	if m.GetMessageFunc != nil {
+		return m.GetMessageFunc(ctx, uuid)
+	}
+	return models.Notification{UUID: uuid, Status: models.StatusPending}, nil
+}
+
+func (m *MockRedisConnection) GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error) {
+	if m.GetHistoryFunc != nil {
+		return m.GetHistoryFunc(ctx, uuid)
+	}
+	return []models.StatusChange{}, nil
+}
+
+func (m *MockRedisConnection) ListMessages(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error) {
+	if m.ListMessagesFunc != nil {
+		return m.ListMessagesFunc(ctx, filter)
+	}
+	return []models.Notification{}, "", nil
+}
+
+func (m *MockRedisConnection) CancelMessage(ctx context.Context, uuid string) error {
+	if m.CancelMessageFunc != nil {
+		return m.CancelMessageFunc(ctx, uuid)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) SaveStatus(ctx context.Context, uuid string, status string) error {
+	if m.SaveStatusFunc != nil {
+		return m.SaveStatusFunc(ctx, uuid, status)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) ListDead(ctx context.Context, limit int) ([]models.DeadLetter, error) {
+	if m.ListDeadFunc != nil {
+		return m.ListDeadFunc(ctx, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockRedisConnection) ReviveDead(ctx context.Context, uuid string) (models.Notification, error) {
+	if m.ReviveDeadFunc != nil {
+		return m.ReviveDeadFunc(ctx, uuid)
+	}
+	return models.Notification{}, models.ErrNotFound
+}
+
+func (m *MockRedisConnection) MarkDead(ctx context.Context, uuid string, at time.Time) error {
+	if m.MarkDeadFunc != nil {
+		return m.MarkDeadFunc(ctx, uuid, at)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+	if m.TransitionFunc != nil {
+		return m.TransitionFunc(ctx, uuid, to, from...)
 	}
 	return models.StatusPending, nil
 }
 
-func (m *MockRedisConnection) DeleteMessage(ctx context.Context, uuid string) error {
-	if m.DeleteMessageFunc != nil {
-		return m.DeleteMessageFunc(ctx, uuid)
+func (m *MockRedisConnection) EditMessage(ctx context.Context, notif models.Notification, version int, newVersion int) error {
+	if m.EditMessageFunc != nil {
+		return m.EditMessageFunc(ctx, notif, version, newVersion)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) ResumeSeries(ctx context.Context, uuid string, version int, fireAt time.Time) error {
+	if m.ResumeSeriesFunc != nil {
+		return m.ResumeSeriesFunc(ctx, uuid, version, fireAt)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) SaveTemplate(ctx context.Context, t models.Template) (models.Template, error) {
+	if m.SaveTemplateFunc != nil {
+		return m.SaveTemplateFunc(ctx, t)
+	}
+	t.Version = 1
+	return t, nil
+}
+
+func (m *MockRedisConnection) GetTemplate(ctx context.Context, name string, version int) (models.Template, error) {
+	if m.GetTemplateFunc != nil {
+		return m.GetTemplateFunc(ctx, name, version)
+	}
+	return models.Template{}, models.ErrNotFound
+}
+
+func (m *MockRedisConnection) SaveFanout(ctx context.Context, parent models.Notification, children []models.Notification) error {
+	if m.SaveFanoutFunc != nil {
+		return m.SaveFanoutFunc(ctx, parent, children)
+	}
+	return nil
+}
+
+func (m *MockRedisConnection) GetFanout(ctx context.Context, uuid string) (models.Notification, error) {
+	if m.GetFanoutFunc != nil {
+		return m.GetFanoutFunc(ctx, uuid)
+	}
+	return models.Notification{}, models.ErrNotFound
+}
+
+func (m *MockRedisConnection) Backlog(ctx context.Context, now time.Time) (map[string]int, error) {
+	if m.BacklogFunc != nil {
+		return m.BacklogFunc(ctx, now)
+	}
+	return map[string]int{}, nil
+}
+
+func (m *MockRedisConnection) EventsAfter(ctx context.Context, id string, limit int) ([]models.StatusEvent, error) {
+	if m.EventsAfterFunc != nil {
+		return m.EventsAfterFunc(ctx, id, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockRedisConnection) SavePreferences(ctx context.Context, prefs models.Preferences) error {
+	if m.preferences == nil {
+		m.preferences = make(map[string]models.Preferences)
 	}
+	m.preferences[prefs.Channel+":"+prefs.Address] = prefs
+	return nil
+}
+
+func (m *MockRedisConnection) GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error) {
+	prefs, ok := m.preferences[channel+":"+address]
+	if !ok {
+		return models.Preferences{}, models.ErrNotFound
+	}
+	return prefs, nil
+}
+
+func (m *MockRedisConnection) DeletePreferences(ctx context.Context, channel string, address string) error {
+	if _, ok := m.preferences[channel+":"+address]; !ok {
+		return models.ErrNotFound
+	}
+	delete(m.preferences, channel+":"+address)
+	return nil
+}
+
+func (m *MockRedisConnection) ReserveIdempotencyKey(ctx context.Context, key string, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error) {
+	if m.idempotency == nil {
+		m.idempotency = make(map[string]models.IdempotencyRecord)
+		m.idempotencyTTL = make(map[string]time.Duration)
+	}
+	if record, exists := m.idempotency[key]; exists {
+		return record, false, nil
+	}
+	record := models.IdempotencyRecord{Fingerprint: fingerprint}
+	m.idempotency[key] = record
+	m.idempotencyTTL[key] = ttl
+	return record, true, nil
+}
+
+func (m *MockRedisConnection) SaveIdempotencyKey(ctx context.Context, key string, record models.IdempotencyRecord, ttl time.Duration) error {
+	m.idempotency[key] = record
+	m.idempotencyTTL[key] = ttl
+	return nil
+}
+
+func (m *MockRedisConnection) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	delete(m.idempotency, key)
 	return nil
 }
 
@@ -86,7 +289,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			mockQueueError:     nil,
 			mockRedisError:     nil,
 			expectedStatusCode: http.StatusCreated,
-			expectedBodyPart:   "Message is created",
+			expectedBodyPart:   `"status":"pending"`,
 		},
 		{
 			name:               "Invalid JSON",
@@ -97,7 +300,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			expectedBodyPart:   "Failed to unmarshal JSON",
 		},
 		{
-			name: "Queue send failure",
+			name: "Queue unavailable",
 			requestBody: models.Notification{
 				UUID: uuid.New().String(),
 				NotificationCard: models.NotificationCard{
@@ -107,8 +310,8 @@ func TestNotificationRequest_POST(t *testing.T) {
 			},
 			mockQueueError:     errors.New("queue connection failed"),
 			mockRedisError:     nil,
-			expectedStatusCode: http.StatusInternalServerError,
-			expectedBodyPart:   "Failed to send notification",
+			expectedStatusCode: http.StatusCreated,
+			expectedBodyPart:   `"status":"pending"`,
 		},
 		{
 			name: "Redis save failure",
@@ -124,6 +327,31 @@ func TestNotificationRequest_POST(t *testing.T) {
 			expectedStatusCode: http.StatusInternalServerError,
 			expectedBodyPart:   "Failed to save notification",
 		},
+		{
+			name: "Duplicate UUID",
+			requestBody: models.Notification{
+				UUID: uuid.New().String(),
+				NotificationCard: models.NotificationCard{
+					Message:     "Test message",
+					ScheduledAt: 5000,
+				},
+			},
+			mockQueueError:     nil,
+			mockRedisError:     models.ErrAlreadyExists,
+			expectedStatusCode: http.StatusConflict,
+			expectedBodyPart:   "already exists",
+		},
+		{
+			name: "Generated UUID",
+			requestBody: models.NotificationCard{
+				Message:     "Test message",
+				ScheduledAt: 5000,
+			},
+			mockQueueError:     nil,
+			mockRedisError:     nil,
+			expectedStatusCode: http.StatusCreated,
+			expectedBodyPart:   `"uuid":"`,
+		},
 		{
 			name: "Empty message",
 			requestBody: models.Notification{
@@ -136,7 +364,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			mockQueueError:     nil,
 			mockRedisError:     nil,
 			expectedStatusCode: http.StatusCreated,
-			expectedBodyPart:   "Message is created",
+			expectedBodyPart:   `"status":"pending"`,
 		},
 		{
 			name: "Zero delay",
@@ -150,7 +378,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			mockQueueError:     nil,
 			mockRedisError:     nil,
 			expectedStatusCode: http.StatusCreated,
-			expectedBodyPart:   "Message is created",
+			expectedBodyPart:   `"status":"pending"`,
 		},
 		{
 			name: "Large delay",
@@ -164,7 +392,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			mockQueueError:     nil,
 			mockRedisError:     nil,
 			expectedStatusCode: http.StatusCreated,
-			expectedBodyPart:   "Message is created",
+			expectedBodyPart:   `"status":"pending"`,
 		},
 	}
 
@@ -173,18 +401,24 @@ func TestNotificationRequest_POST(t *testing.T) {
 			ctx, mockQueue, mockRedis := createMockDependencies()
 
 			// Setup mock functions
+			published := 0
 			mockQueue.SendMessageFunc = func(notification models.Notification) error {
+				published++
 				return tt.mockQueueError
 			}
 
+			var saved models.Notification
 			mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
 				// Verify status was set to pending
 				if notif.Status != models.StatusPending && tt.mockRedisError == nil {
 					t.Errorf("Expected status to be 'pending', got '%s'", notif.Status)
 				}
+				if notif.UUID == "" {
+					t.Error("Expected UUID to be generated")
+				}
+				saved = notif
 				return tt.mockRedisError
 			}
-
 			// Create request body
 			var body []byte
 			if str, ok := tt.requestBody.(string); ok {
@@ -201,7 +435,7 @@ func TestNotificationRequest_POST(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			// Call handler using the interface types
-			CreateNotification(ctx, mockQueue, mockRedis, w, req)
+			CreateNotification(ctx, mockQueue, mockRedis, true, w, req)
 
 			// Check status code
 			if w.Code != tt.expectedStatusCode {
@@ -213,6 +447,16 @@ func TestNotificationRequest_POST(t *testing.T) {
 			if tt.expectedBodyPart != "" && !containsString(responseBody, tt.expectedBodyPart) {
 				t.Errorf("Expected response to contain '%s', got '%s'", tt.expectedBodyPart, responseBody)
 			}
+
+			if w.Code == http.StatusCreated {
+				if location := w.Header().Get("Location"); location != "/notify/"+saved.UUID {
+					t.Errorf("Expected Location '/notify/%s', got '%s'", saved.UUID, location)
+				}
+			}
+			// в очередь уведомление публикует outbox relay, а не обработчик
+			if published != 0 {
+				t.Errorf("Expected no direct publish, got %d", published)
+			}
 		})
 	}
 }
@@ -255,8 +499,8 @@ func TestNotificationRequest_GET(t *testing.T) {
 			name:               "Notification not found",
 			notificationID:     uuid.New().String(),
 			mockStatus:         "",
-			mockRedisError:     errors.New("notification not found"),
-			expectedStatusCode: http.StatusInternalServerError,
+			mockRedisError:     models.ErrNotFound,
+			expectedStatusCode: http.StatusNotFound,
 			expectedStatus:     "",
 		},
 		{
@@ -282,11 +526,23 @@ func TestNotificationRequest_GET(t *testing.T) {
 			ctx, _, mockRedis := createMockDependencies()
 
 			// Setup mock function
-			mockRedis.GetStatusFunc = func(ctx context.Context, uuid string) (string, error) {
+			mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
 				if uuid != tt.notificationID {
 					t.Errorf("Expected UUID '%s', got '%s'", tt.notificationID, uuid)
 				}
-				return tt.mockStatus, tt.mockRedisError
+				notification := models.Notification{UUID: uuid, Status: tt.mockStatus, Attempts: 1, LastError: "smtp is down"}
+				notification.Message = "Test message"
+				notification.Channel = models.ChannelEmail
+				notification.Recipient = "user@example.com"
+				return notification, tt.mockRedisError
+			}
+			firedAt := time.Date(2025, 3, 1, 12, 0, 5, 0, time.UTC)
+			mockRedis.GetHistoryFunc = func(ctx context.Context, uuid string) ([]models.StatusChange, error) {
+				return []models.StatusChange{
+					{Status: models.StatusPending, At: firedAt.Add(-5 * time.Second)},
+					{Status: models.StatusProcessing, At: firedAt},
+					{Status: tt.mockStatus, At: firedAt.Add(time.Second)},
+				}, nil
 			}
 
 			// Create request
@@ -306,14 +562,23 @@ func TestNotificationRequest_GET(t *testing.T) {
 
 			// Check response body for successful requests
 			if tt.expectedStatusCode == http.StatusOK {
-				var response map[string]string
+				var response notificationResponse
 				err := json.Unmarshal(w.Body.Bytes(), &response)
 				if err != nil {
 					t.Errorf("Failed to unmarshal response: %v", err)
 				}
 
-				if response["status"] != tt.expectedStatus {
-					t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, response["status"])
+				if response.Status != tt.expectedStatus {
+					t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, response.Status)
+				}
+				if response.Message != "Test message" || response.Recipient != "user@example.com" || response.LastError != "smtp is down" {
+					t.Errorf("Expected full notification record, got %s", w.Body.String())
+				}
+				if len(response.History) != 3 || response.History[2].Status != tt.expectedStatus {
+					t.Errorf("Expected status history, got %v", response.History)
+				}
+				if !response.FiredAt.Equal(firedAt) {
+					t.Errorf("Expected fired_at %s, got %s", firedAt, response.FiredAt)
 				}
 
 				// Check Content-Type header
@@ -334,20 +599,23 @@ func TestNotificationRequest_DELETE(t *testing.T) {
 		mockRedisError     error
 		expectedStatusCode int
 		expectedBodyPart   string
+		expectQueueCancel  bool
 	}{
 		{
-			name:               "Successful deletion",
+			name:               "Successful cancellation",
 			notificationID:     uuid.New().String(),
 			mockRedisError:     nil,
-			expectedStatusCode: http.StatusAccepted,
-			expectedBodyPart:   "Notification deletion in progress",
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"status":"cancelled"`,
+			expectQueueCancel:  true,
 		},
 		{
-			name:               "Delete with valid UUID",
+			name:               "Cancel with valid UUID",
 			notificationID:     "550e8400-e29b-41d4-a716-446655440000",
 			mockRedisError:     nil,
-			expectedStatusCode: http.StatusAccepted,
-			expectedBodyPart:   "Notification deletion in progress",
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"status":"cancelled"`,
+			expectQueueCancel:  true,
 		},
 		{
 			name:               "Empty UUID",
@@ -357,26 +625,52 @@ func TestNotificationRequest_DELETE(t *testing.T) {
 			expectedBodyPart:   "Invalid request",
 		},
 		{
-			name:               "Redis deletion error (async, still returns 202)",
+			name:               "Unknown notification",
 			notificationID:     uuid.New().String(),
-			mockRedisError:     errors.New("redis delete failed"),
-			expectedStatusCode: http.StatusAccepted,
-			expectedBodyPart:   "Notification deletion in progress",
+			mockRedisError:     models.ErrNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBodyPart:   "Notification is not found",
+		},
+		{
+			name:               "Already sent notification",
+			notificationID:     uuid.New().String(),
+			mockRedisError:     fmt.Errorf("%w: status is %q", models.ErrStatusConflict, models.StatusSent),
+			expectedStatusCode: http.StatusConflict,
+			expectedBodyPart:   "sent",
+		},
+		{
+			name:               "Notification is being processed",
+			notificationID:     uuid.New().String(),
+			mockRedisError:     fmt.Errorf("%w: status is %q", models.ErrStatusConflict, models.StatusProcessing),
+			expectedStatusCode: http.StatusConflict,
+			expectedBodyPart:   "processing",
+		},
+		{
+			name:               "Redis error",
+			notificationID:     uuid.New().String(),
+			mockRedisError:     errors.New("redis connection failed"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBodyPart:   "Failed to cancel notification",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx, _, mockRedis := createMockDependencies()
+			ctx, mockQueue, mockRedis := createMockDependencies()
 
-			deleteCalled := false
-			mockRedis.DeleteMessageFunc = func(ctx context.Context, uuid string) error {
-				deleteCalled = true
+			cancelCalled := false
+			mockRedis.CancelMessageFunc = func(ctx context.Context, uuid string) error {
+				cancelCalled = true
 				if uuid != tt.notificationID {
 					t.Errorf("Expected UUID '%s', got '%s'", tt.notificationID, uuid)
 				}
 				return tt.mockRedisError
 			}
+			queueCancelled := false
+			mockQueue.CancelMessageDelayFunc = func(uuid string) error {
+				queueCancelled = true
+				return nil
+			}
 
 			// Create request
 			req := httptest.NewRequest(http.MethodDelete, "/notify/"+tt.notificationID, nil)
@@ -386,7 +680,7 @@ func TestNotificationRequest_DELETE(t *testing.T) {
 			w := httptest.NewRecorder()
 
 			// Call handler
-			DeleteNotification(ctx, mockRedis, w, req)
+			DeleteNotification(ctx, mockQueue, mockRedis, w, req)
 
 			// Check status code
 			if w.Code != tt.expectedStatusCode {
@@ -399,16 +693,13 @@ func TestNotificationRequest_DELETE(t *testing.T) {
 				t.Errorf("Expected response to contain '%s', got '%s'", tt.expectedBodyPart, responseBody)
 			}
 
-			// For valid requests, verify delete was NOT called synchronously (it's async)
-			// We can't easily test the goroutine, but we verify the response is immediate
-			if tt.expectedStatusCode == http.StatusAccepted && tt.notificationID != "" {
-				// The response should be immediate, before goroutine executes
-				// This is inherent in the async design
+			if queueCancelled != tt.expectQueueCancel {
+				t.Errorf("Expected queue cancel: %v, got %v", tt.expectQueueCancel, queueCancelled)
 			}
 
-			// For empty UUID, delete should not be called
-			if tt.notificationID == "" && deleteCalled {
-				t.Error("Delete should not be called for empty UUID")
+			// For empty UUID, cancel should not be called
+			if tt.notificationID == "" && cancelCalled {
+				t.Error("Cancel should not be called for empty UUID")
 			}
 		})
 	}
@@ -426,7 +717,7 @@ func TestNotificationRequest_MethodRouting(t *testing.T) {
 		{
 			name:               "POST method routes correctly",
 			method:             http.MethodPost,
-			requestBody:        `{"uuid":"test-uuid","message":"test","scheduled_at":5000}`,
+			requestBody:        `{"uuid":"6f1c3a52-8d1e-4c53-9b6e-2f7c1d9e4a10","message":"test","scheduled_at":5000}`,
 			pathID:             "",
 			expectedStatusCode: http.StatusCreated,
 		},
@@ -442,7 +733,7 @@ func TestNotificationRequest_MethodRouting(t *testing.T) {
 			method:             http.MethodDelete,
 			requestBody:        "",
 			pathID:             uuid.New().String(),
-			expectedStatusCode: http.StatusAccepted,
+			expectedStatusCode: http.StatusOK,
 		},
 		{
 			name:               "PUT method not allowed",
@@ -485,10 +776,10 @@ func TestNotificationRequest_MethodRouting(t *testing.T) {
 			mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
 				return nil
 			}
-			mockRedis.GetStatusFunc = func(ctx context.Context, uuid string) (string, error) {
-				return models.StatusPending, nil
+			mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+				return models.Notification{UUID: uuid, Status: models.StatusPending}, nil
 			}
-			mockRedis.DeleteMessageFunc = func(ctx context.Context, uuid string) error {
+			mockRedis.CancelMessageFunc = func(ctx context.Context, uuid string) error {
 				return nil
 			}
 
@@ -509,11 +800,11 @@ func TestNotificationRequest_MethodRouting(t *testing.T) {
 			handler := func(w http.ResponseWriter, r *http.Request) {
 				switch r.Method {
 				case http.MethodPost:
-					CreateNotification(ctx, mockQueue, mockRedis, w, r)
+					CreateNotification(ctx, mockQueue, mockRedis, true, w, r)
 				case http.MethodGet:
 					GetNotificationStatus(ctx, mockRedis, w, r)
 				case http.MethodDelete:
-					DeleteNotification(ctx, mockRedis, w, r)
+					DeleteNotification(ctx, mockQueue, mockRedis, w, r)
 				default:
 					w.WriteHeader(http.StatusMethodNotAllowed)
 				}
@@ -546,15 +837,20 @@ func TestNotificationRequest_Integration(t *testing.T) {
 		return nil
 	}
 
-	mockRedis.GetStatusFunc = func(ctx context.Context, uuid string) (string, error) {
+	mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
 		if notif, exists := savedNotifications[uuid]; exists {
-			return notif.Status, nil
+			return notif, nil
 		}
-		return "", errors.New("not found")
+		return models.Notification{}, errors.New("not found")
 	}
 
-	mockRedis.DeleteMessageFunc = func(ctx context.Context, uuid string) error {
-		delete(savedNotifications, uuid)
+	mockRedis.CancelMessageFunc = func(ctx context.Context, uuid string) error {
+		notif, exists := savedNotifications[uuid]
+		if !exists {
+			return models.ErrNotFound
+		}
+		notif.Status = models.StatusCancelled
+		savedNotifications[uuid] = notif
 		return nil
 	}
 
@@ -574,7 +870,7 @@ func TestNotificationRequest_Integration(t *testing.T) {
 
 	createReq := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBuffer(bodyBytes))
 	createW := httptest.NewRecorder()
-	CreateNotification(ctx, mockQueue, mockRedis, createW, createReq)
+	CreateNotification(ctx, mockQueue, mockRedis, true, createW, createReq)
 
 	if createW.Code != http.StatusCreated {
 		t.Errorf("Create failed: expected %d, got %d", http.StatusCreated, createW.Code)
@@ -590,20 +886,36 @@ func TestNotificationRequest_Integration(t *testing.T) {
 		t.Errorf("Get failed: expected %d, got %d", http.StatusOK, getW.Code)
 	}
 
-	var statusResp map[string]string
+	var statusResp notificationResponse
 	json.Unmarshal(getW.Body.Bytes(), &statusResp)
-	if statusResp["status"] != models.StatusPending {
-		t.Errorf("Expected status 'pending', got '%s'", statusResp["status"])
+	if statusResp.Status != models.StatusPending {
+		t.Errorf("Expected status 'pending', got '%s'", statusResp.Status)
+	}
+	if statusResp.FireAt.IsZero() {
+		t.Error("Expected fire_at to be set")
 	}
 
 	// Test 3: Delete notification
 	deleteReq := httptest.NewRequest(http.MethodDelete, "/notify/"+notifID, nil)
 	deleteReq.SetPathValue("id", notifID)
 	deleteW := httptest.NewRecorder()
-	DeleteNotification(ctx, mockRedis, deleteW, deleteReq)
+	DeleteNotification(ctx, mockQueue, mockRedis, deleteW, deleteReq)
+
+	if deleteW.Code != http.StatusOK {
+		t.Errorf("Delete failed: expected %d, got %d", http.StatusOK, deleteW.Code)
+	}
+	if savedNotifications[notifID].Status != models.StatusCancelled {
+		t.Errorf("Expected status 'cancelled', got '%s'", savedNotifications[notifID].Status)
+	}
+
+	// Test 4: Cancel unknown notification
+	unknownReq := httptest.NewRequest(http.MethodDelete, "/notify/unknown", nil)
+	unknownReq.SetPathValue("id", "unknown")
+	unknownW := httptest.NewRecorder()
+	DeleteNotification(ctx, mockQueue, mockRedis, unknownW, unknownReq)
 
-	if deleteW.Code != http.StatusAccepted {
-		t.Errorf("Delete failed: expected %d, got %d", http.StatusAccepted, deleteW.Code)
+	if unknownW.Code != http.StatusNotFound {
+		t.Errorf("Delete of unknown id: expected %d, got %d", http.StatusNotFound, unknownW.Code)
 	}
 }
 
@@ -649,9 +961,52 @@ func TestCreateNotification_EdgeCases(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:        "Relative callback url",
+			requestBody: `{"message":"Test","scheduled_at":5000,"callback_url":"/hooks/status"}`,
+			setupMock:   func(mq *MockQueueProps, mr *MockRedisConnection) {},
+			checkResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected %d, got %d", http.StatusBadRequest, w.Code)
+				}
+			},
+		},
+		{
+			name:        "UUID colliding with a route",
+			requestBody: `{"uuid":"events","message":"Test","scheduled_at":5000}`,
+			setupMock:   func(mq *MockQueueProps, mr *MockRedisConnection) {},
+			checkResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected %d, got %d", http.StatusBadRequest, w.Code)
+				}
+			},
+		},
+		{
+			name:        "UUID without dashes",
+			requestBody: `{"uuid":"6f1c3a528d1e4c539b6e2f7c1d9e4a10","message":"Test","scheduled_at":5000}`,
+			setupMock:   func(mq *MockQueueProps, mr *MockRedisConnection) {},
+			checkResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected %d, got %d", http.StatusBadRequest, w.Code)
+				}
+			},
+		},
+		{
+			name:        "Unknown priority",
+			requestBody: `{"message":"Test","scheduled_at":5000,"priority":"critical"}`,
+			setupMock:   func(mq *MockQueueProps, mr *MockRedisConnection) {},
+			checkResult: func(t *testing.T, w *httptest.ResponseRecorder) {
+				if w.Code != http.StatusBadRequest {
+					t.Errorf("Expected %d, got %d", http.StatusBadRequest, w.Code)
+				}
+				if !containsString(w.Body.String(), "Unknown priority") {
+					t.Error("Expected priority error message")
+				}
+			},
+		},
 		{
 			name:        "Special characters in message",
-			requestBody: `{"uuid":"test-uuid","message":"Test with emoji 🚀 and special chars <>&\"","scheduled_at":5000}`,
+			requestBody: `{"uuid":"6f1c3a52-8d1e-4c53-9b6e-2f7c1d9e4a10","message":"Test with emoji 🚀 and special chars <>&\"","scheduled_at":5000}`,
 			setupMock: func(mq *MockQueueProps, mr *MockRedisConnection) {
 				mq.SendMessageFunc = func(notification models.Notification) error {
 					if notification.NotificationCard.Message != "Test with emoji 🚀 and special chars <>&\"" {
@@ -679,14 +1034,1407 @@ func TestCreateNotification_EdgeCases(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBufferString(tt.requestBody))
 			w := httptest.NewRecorder()
 
-			CreateNotification(ctx, mockQueue, mockRedis, w, req)
+			CreateNotification(ctx, mockQueue, mockRedis, true, w, req)
 
 			tt.checkResult(t, w)
 		})
 	}
 }
 
+// TestCreateNotification_CallbacksDisabled tests that callback_url is rejected without a signing secret
+func TestCreateNotification_CallbacksDisabled(t *testing.T) {
+	ctx, mockQueue, mockRedis := createMockDependencies()
+	mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
+		t.Errorf("Notification with callback_url must not be saved: %+v", notif)
+		return nil
+	}
+
+	body := `{"message":"Test","scheduled_at":5000,"callback_url":"https://example.com/hooks"}`
+	req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	CreateNotification(ctx, mockQueue, mockRedis, false, w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
 // Helper function to check if string contains substring
 func containsString(s, substr string) bool {
 	return bytes.Contains([]byte(s), []byte(substr))
 }
+
+// TestListDeadNotifications tests GET /notify/dead endpoint
+func TestListDeadNotifications(t *testing.T) {
+	ctx, _, mockRedis := createMockDependencies()
+
+	failedAt := time.Now()
+	mockRedis.ListDeadFunc = func(ctx context.Context, limit int) ([]models.DeadLetter, error) {
+		if limit != 10 {
+			t.Errorf("Expected limit 10, got %d", limit)
+		}
+		return []models.DeadLetter{{
+			Notification: models.Notification{UUID: "dead-uuid", Status: models.StatusFailed, LastError: "smtp: 550 No such user"},
+			FailedAt:     failedAt,
+			History:      []models.Attempt{{Number: 1, At: failedAt, Error: "smtp: 550 No such user"}},
+		}}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notify/dead?limit=10", nil)
+	w := httptest.NewRecorder()
+	ListDeadNotifications(ctx, mockRedis, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response []models.DeadLetter
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response) != 1 || response[0].UUID != "dead-uuid" || len(response[0].History) != 1 {
+		t.Errorf("Unexpected response: %s", w.Body.String())
+	}
+
+	// Invalid limit
+	req = httptest.NewRequest(http.MethodGet, "/notify/dead?limit=-1", nil)
+	w = httptest.NewRecorder()
+	ListDeadNotifications(ctx, mockRedis, w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestReplayDeadNotifications tests POST /notify/{id}/replay and POST /notify/dead/replay endpoints
+func TestReplayDeadNotifications(t *testing.T) {
+	tests := []struct {
+		name               string
+		pathID             string
+		requestBody        string
+		storeError         error
+		expectedStatusCode int
+		expectedReplayed   []string
+	}{
+		{
+			name:               "Replay one notification",
+			pathID:             "dead-1",
+			expectedStatusCode: http.StatusAccepted,
+			expectedReplayed:   []string{"dead-1"},
+		},
+		{
+			name:               "Replay unknown notification",
+			pathID:             "unknown",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Store failure",
+			pathID:             "dead-1",
+			storeError:         errors.New("redis connection failed"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+		{
+			name:               "Replay selected notifications",
+			requestBody:        `{"ids":["dead-1","unknown"]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedReplayed:   []string{"dead-1"},
+		},
+		{
+			name:               "Replay all notifications",
+			expectedStatusCode: http.StatusOK,
+			expectedReplayed:   []string{"dead-1", "dead-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+
+			dead := map[string]bool{"dead-1": true, "dead-2": true}
+			mockRedis.ListDeadFunc = func(ctx context.Context, limit int) ([]models.DeadLetter, error) {
+				return []models.DeadLetter{
+					{Notification: models.Notification{UUID: "dead-1"}},
+					{Notification: models.Notification{UUID: "dead-2"}},
+				}, nil
+			}
+			var replayed []string
+			mockRedis.ReviveDeadFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+				if tt.storeError != nil {
+					return models.Notification{}, tt.storeError
+				}
+				if !dead[uuid] {
+					return models.Notification{}, models.ErrNotFound
+				}
+				replayed = append(replayed, uuid)
+				return models.Notification{UUID: uuid, Status: models.StatusPending}, nil
+			}
+			// возрождённое уведомление публикует outbox relay, а не обработчик
+			mockQueue.SendMessageFunc = func(notification models.Notification) error {
+				t.Errorf("Unexpected publish of %s", notification.UUID)
+				return nil
+			}
+
+			target := "/notify/dead/replay"
+			if tt.pathID != "" {
+				target = "/notify/" + tt.pathID + "/replay"
+			}
+			req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(tt.requestBody))
+			if tt.pathID != "" {
+				req.SetPathValue("id", tt.pathID)
+			}
+			w := httptest.NewRecorder()
+
+			ReplayDeadNotifications(ctx, mockRedis, w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatusCode, w.Code)
+			}
+			if len(replayed) != len(tt.expectedReplayed) {
+				t.Fatalf("Expected replayed %v, got %v", tt.expectedReplayed, replayed)
+			}
+			for i := range replayed {
+				if replayed[i] != tt.expectedReplayed[i] {
+					t.Errorf("Expected replayed %v, got %v", tt.expectedReplayed, replayed)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveSchedule(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		card          models.NotificationCard
+		maxDelay      time.Duration
+		expectError   bool
+		expectedFire  time.Time
+		expectedDelay int64
+	}{
+		{
+			name:          "Relative delay",
+			card:          models.NotificationCard{ScheduledAt: 5000},
+			expectedFire:  now.Add(5 * time.Second),
+			expectedDelay: 5000,
+		},
+		{
+			name:        "Negative delay",
+			card:        models.NotificationCard{ScheduledAt: -1},
+			expectError: true,
+		},
+		{
+			name:          "RFC 3339 with offset",
+			card:          models.NotificationCard{SendAt: "2025-03-01T15:30:00+03:00"},
+			expectedFire:  now.Add(30 * time.Minute),
+			expectedDelay: (30 * time.Minute).Milliseconds(),
+		},
+		{
+			name:          "Wall clock with timezone",
+			card:          models.NotificationCard{SendAt: "2025-03-01T13:00", Timezone: "Europe/Berlin"},
+			expectedFire:  now,
+			expectedDelay: 0,
+		},
+		{
+			name:        "Wall clock without timezone",
+			card:        models.NotificationCard{SendAt: "2025-03-01T13:00"},
+			expectError: true,
+		},
+		{
+			name:        "Unknown timezone",
+			card:        models.NotificationCard{SendAt: "2025-03-01T13:00", Timezone: "Mars/Olympus"},
+			expectError: true,
+		},
+		{
+			name:        "Past time is rejected",
+			card:        models.NotificationCard{SendAt: "2025-03-01T11:00:00Z"},
+			expectError: true,
+		},
+		{
+			name:          "Past time is allowed",
+			card:          models.NotificationCard{SendAt: "2025-03-01T11:00:00Z", AllowPast: true},
+			expectedFire:  now.Add(-time.Hour),
+			expectedDelay: 0,
+		},
+		{
+			name:        "Both send_at and scheduled_at",
+			card:        models.NotificationCard{SendAt: "2025-03-01T13:00:00Z", ScheduledAt: 1000},
+			expectError: true,
+		},
+		{
+			name:          "Series starts at the next occurrence",
+			card:          models.NotificationCard{Cron: "*/15 * * * *"},
+			expectedFire:  now.Add(15 * time.Minute),
+			expectedDelay: (15 * time.Minute).Milliseconds(),
+		},
+		{
+			name:          "Series with start time and timezone",
+			card:          models.NotificationCard{Cron: "0 10 * * MON-FRI", SendAt: "2025-03-03T10:00", Timezone: "Europe/Moscow"},
+			expectedFire:  time.Date(2025, 3, 3, 7, 0, 0, 0, time.UTC),
+			expectedDelay: (43 * time.Hour).Milliseconds(),
+		},
+		{
+			name:        "Series with invalid cron",
+			card:        models.NotificationCard{Cron: "0 25 * * *"},
+			expectError: true,
+		},
+		{
+			name:        "Series ending before the first occurrence",
+			card:        models.NotificationCard{Cron: "0 10 * * *", Until: now.Add(time.Hour)},
+			expectError: true,
+		},
+		{
+			name:        "Series with scheduled_at",
+			card:        models.NotificationCard{Cron: "0 10 * * *", ScheduledAt: 1000},
+			expectError: true,
+		},
+		{
+			name:        "Beyond delayed exchange limit",
+			card:        models.NotificationCard{SendAt: "2025-06-01T12:00:00Z"},
+			maxDelay:    models.MaxDelay,
+			expectError: true,
+		},
+		{
+			name:        "Relative delay overflowing time.Duration",
+			card:        models.NotificationCard{ScheduledAt: 9300000000000},
+			expectError: true,
+		},
+		{
+			name:        "Beyond schedule ceiling",
+			card:        models.NotificationCard{SendAt: "2036-03-01T12:00:00Z"},
+			expectError: true,
+		},
+		{
+			name:          "Scheduler without delay limit",
+			card:          models.NotificationCard{SendAt: "2025-06-01T12:00:00Z"},
+			expectedFire:  time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+			expectedDelay: (92 * 24 * time.Hour).Milliseconds(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := models.Notification{NotificationCard: tt.card}
+			err := resolveSchedule(&notification, now, tt.maxDelay)
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got fire_at %s", notification.FireAt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !notification.FireAt.Equal(tt.expectedFire) {
+				t.Errorf("Expected fire_at %s, got %s", tt.expectedFire, notification.FireAt)
+			}
+			if notification.ScheduledAt != tt.expectedDelay {
+				t.Errorf("Expected delay %d, got %d", tt.expectedDelay, notification.ScheduledAt)
+			}
+		})
+	}
+}
+
+// TestSeriesEndpoints tests pause, resume and occurrences of a recurring notification
+func TestSeriesEndpoints(t *testing.T) {
+	ctx, mockQueue, mockRedis := createMockDependencies()
+
+	series := models.Notification{UUID: "series-uuid", Status: models.StatusPending, FireAt: time.Now().Add(time.Minute)}
+	series.Cron = "*/15 * * * *"
+	series.MaxOccurrences = 5
+	series.Occurrences = 2
+
+	mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+		switch uuid {
+		case series.UUID:
+			return series, nil
+		case "one-shot":
+			return models.Notification{UUID: uuid, Status: models.StatusPending}, nil
+		}
+		return models.Notification{}, models.ErrNotFound
+	}
+	mockRedis.TransitionFunc = func(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+		for _, status := range from {
+			if series.Status == status {
+				previous := series.Status
+				series.Status = to
+				return previous, nil
+			}
+		}
+		return series.Status, models.ErrStatusConflict
+	}
+	mockRedis.ResumeSeriesFunc = func(ctx context.Context, uuid string, version int, fireAt time.Time) error {
+		if series.Status != models.StatusPaused || series.Version != version {
+			return models.ErrStatusConflict
+		}
+		series.Status = models.StatusPending
+		series.FireAt = fireAt
+		series.Version++
+		return nil
+	}
+	// следующее срабатывание публикует outbox relay, а не обработчик
+	mockQueue.SendMessageFunc = func(notification models.Notification) error {
+		t.Errorf("Unexpected publish of version %d", notification.Version)
+		return nil
+	}
+
+	call := func(handler func(w http.ResponseWriter, r *http.Request), id string, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	pause := func(w http.ResponseWriter, r *http.Request) { PauseSeries(ctx, mockRedis, w, r) }
+	resume := func(w http.ResponseWriter, r *http.Request) { ResumeSeries(ctx, mockQueue, mockRedis, w, r) }
+	occurrences := func(w http.ResponseWriter, r *http.Request) { ListOccurrences(ctx, mockRedis, w, r) }
+
+	// Upcoming occurrences are limited by max_occurrences
+	w := call(occurrences, series.UUID, "/notify/series-uuid/occurrences?limit=10")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Occurrences: expected %d, got %d", http.StatusOK, w.Code)
+	}
+	var response occurrencesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Occurrences) != 3 {
+		t.Errorf("Expected 3 upcoming occurrences, got %v", response.Occurrences)
+	}
+
+	if w := call(pause, series.UUID, "/notify/series-uuid/pause"); w.Code != http.StatusOK {
+		t.Errorf("Pause: expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if series.Status != models.StatusPaused {
+		t.Errorf("Expected status 'paused', got '%s'", series.Status)
+	}
+	if w := call(pause, series.UUID, "/notify/series-uuid/pause"); w.Code != http.StatusConflict {
+		t.Errorf("Second pause: expected %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	if w := call(resume, series.UUID, "/notify/series-uuid/resume"); w.Code != http.StatusOK {
+		t.Errorf("Resume: expected %d, got %d", http.StatusOK, w.Code)
+	}
+	if series.Status != models.StatusPending {
+		t.Errorf("Expected status 'pending', got '%s'", series.Status)
+	}
+	if !series.FireAt.After(time.Now()) || series.FireAt.Minute()%15 != 0 {
+		t.Errorf("Expected next occurrence to be scheduled, got %s", series.FireAt)
+	}
+	// сообщение, опубликованное до паузы, устарело
+	if series.Version != 1 {
+		t.Errorf("Expected schedule version 1, got %d", series.Version)
+	}
+	if w := call(resume, series.UUID, "/notify/series-uuid/resume"); w.Code != http.StatusConflict {
+		t.Errorf("Second resume: expected %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	if w := call(pause, "one-shot", "/notify/one-shot/pause"); w.Code != http.StatusBadRequest {
+		t.Errorf("Pause of one-shot notification: expected %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := call(pause, "unknown", "/notify/unknown/pause"); w.Code != http.StatusNotFound {
+		t.Errorf("Pause of unknown notification: expected %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestCreateNotification_Idempotency tests replay of POST /notify with an Idempotency-Key
+func TestCreateNotification_Idempotency(t *testing.T) {
+	ctx, mockQueue, mockRedis := createMockDependencies()
+
+	saved := 0
+	saveErr := errors.New("redis connection failed")
+	mockRedis.SaveMessageFunc = func(ctx context.Context, notification models.Notification) error {
+		if saveErr != nil {
+			return saveErr
+		}
+		saved++
+		return nil
+	}
+
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBufferString(body))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		CreateNotification(ctx, mockQueue, mockRedis, true, w, req)
+		return w
+	}
+	body := `{"message":"Idempotent message","scheduled_at":5000}`
+
+	// Failed request releases the key
+	if w := post("key-1", body); w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	saveErr = nil
+
+	first := post("key-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d", http.StatusCreated, first.Code)
+	}
+
+	retry := post("key-1", body)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("Retry: expected %d, got %d", http.StatusCreated, retry.Code)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != first.Header().Get("Location") {
+		t.Errorf("Retry returned a different response: %s", retry.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("Expected Idempotent-Replayed header")
+	}
+	if saved != 1 {
+		t.Errorf("Expected notification to be saved once, got %d", saved)
+	}
+	if ttl := mockRedis.idempotencyTTL["key-1"]; ttl != idempotencyTTL {
+		t.Errorf("Expected saved response to be kept for %s, got %s", idempotencyTTL, ttl)
+	}
+
+	if w := post("key-1", `{"message":"Other message"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Reuse with other body: expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	// Same key and body on the batch endpoint is a different request
+	req := httptest.NewRequest(http.MethodPost, "/notify/batch", bytes.NewBufferString(body))
+	req.Header.Set("Idempotency-Key", "key-1")
+	w := httptest.NewRecorder()
+	CreateNotifications(ctx, mockQueue, mockRedis, true, w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Reuse on batch endpoint: expected %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	// Request in progress holds only a short lease
+	mockRedis.SaveMessageFunc = func(ctx context.Context, notification models.Notification) error {
+		if ttl := mockRedis.idempotencyTTL["key-2"]; ttl != idempotencyLease {
+			t.Errorf("Expected key to be reserved for %s, got %s", idempotencyLease, ttl)
+		}
+		return nil
+	}
+	if w := post("key-2", body); w.Code != http.StatusCreated {
+		t.Fatalf("Expected %d, got %d", http.StatusCreated, w.Code)
+	}
+	inProgress := httptest.NewRequest(http.MethodPost, "/notify", nil)
+	mockRedis.idempotency["key-3"] = models.IdempotencyRecord{Fingerprint: fingerprintOf(inProgress, []byte(body))}
+	if w := post("key-3", body); w.Code != http.StatusConflict {
+		t.Errorf("In progress: expected %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+// TestListNotifications tests GET /notify filters and pagination
+func TestListNotifications(t *testing.T) {
+	tests := []struct {
+		name               string
+		query              string
+		mockError          error
+		expectedStatusCode int
+		expectedFilter     models.ListFilter
+	}{
+		{
+			name:               "Default filter",
+			query:              "",
+			expectedStatusCode: http.StatusOK,
+			expectedFilter:     models.ListFilter{Limit: defaultListLimit},
+		},
+		{
+			name:               "All filters",
+			query:              "?status=pending&from=2025-03-01T00:00:00Z&to=2025-03-02T00:00:00Z&recipient=user@example.com&tag=billing&limit=2&cursor=abc",
+			expectedStatusCode: http.StatusOK,
+			expectedFilter: models.ListFilter{
+				Status:    models.StatusPending,
+				From:      time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC),
+				Recipient: "user@example.com",
+				Tag:       "billing",
+				Limit:     2,
+				Cursor:    "abc",
+			},
+		},
+		{
+			name:               "Unknown status",
+			query:              "?status=lost",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid time window",
+			query:              "?from=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Limit is too large",
+			query:              "?limit=100000",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid cursor",
+			query:              "?cursor=broken",
+			mockError:          models.ErrInvalidCursor,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Redis failure",
+			mockError:          errors.New("redis connection failed"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+
+			mockRedis.ListMessagesFunc = func(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error) {
+				if tt.mockError != nil {
+					return nil, "", tt.mockError
+				}
+				if filter != tt.expectedFilter {
+					t.Errorf("Expected filter %+v, got %+v", tt.expectedFilter, filter)
+				}
+				return []models.Notification{{UUID: "first"}, {UUID: "second"}}, "next-page", nil
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/notify"+tt.query, nil)
+			w := httptest.NewRecorder()
+			NotificationRequest(ctx, mockQueue, mockRedis, true)(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tt.expectedStatusCode, w.Code)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response listResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(response.Items) != 2 || response.NextCursor != "next-page" {
+				t.Errorf("Unexpected response: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestEditNotification tests PATCH /notify/{id}
+func TestEditNotification(t *testing.T) {
+	tests := []struct {
+		name               string
+		requestBody        string
+		status             string
+		cron               string
+		editError          error
+		expectedStatusCode int
+		expectedMessage    string
+		expectedVersion    int
+	}{
+		{
+			name:               "Edit message only",
+			requestBody:        `{"message":"Fixed text"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedMessage:    "Fixed text",
+			expectedVersion:    3,
+		},
+		{
+			name:               "Reschedule",
+			requestBody:        `{"scheduled_at":60000}`,
+			expectedStatusCode: http.StatusOK,
+			expectedMessage:    "Original text",
+			expectedVersion:    4,
+		},
+		{
+			name:               "Reschedule beyond the delay limit",
+			requestBody:        `{"message":"Moved","send_at":"2999-01-01T10:00","timezone":"Europe/Moscow"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Reschedule to the past",
+			requestBody:        `{"send_at":"2000-01-01T10:00:00Z"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Empty patch",
+			requestBody:        `{}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Timezone without send_at",
+			requestBody:        `{"timezone":"Europe/Moscow"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Notification is already sent",
+			requestBody:        `{"message":"Too late"}`,
+			status:             models.StatusSent,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "Concurrent edit",
+			requestBody:        `{"scheduled_at":60000}`,
+			editError:          models.ErrStatusConflict,
+			expectedStatusCode: http.StatusConflict,
+		},
+		{
+			name:               "Series schedule can not be patched",
+			requestBody:        `{"scheduled_at":60000}`,
+			cron:               "0 10 * * *",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+
+			status := tt.status
+			if status == "" {
+				status = models.StatusPending
+			}
+			mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+				notification := models.Notification{UUID: uuid, Status: status, Version: 3}
+				notification.Message = "Original text"
+				notification.Cron = tt.cron
+				return notification, nil
+			}
+			var edits [][2]int
+			mockRedis.EditMessageFunc = func(ctx context.Context, notif models.Notification, version int, newVersion int) error {
+				edits = append(edits, [2]int{version, newVersion})
+				return tt.editError
+			}
+			// перенесённое уведомление публикует outbox relay, а не обработчик
+			mockQueue.SendMessageFunc = func(notification models.Notification) error {
+				t.Errorf("Unexpected publish of version %d", notification.Version)
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPatch, "/notify/patched", bytes.NewBufferString(tt.requestBody))
+			req.SetPathValue("id", "patched")
+			w := httptest.NewRecorder()
+			NotificationRequest(ctx, mockQueue, mockRedis, true)(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if len(edits) != 1 || edits[0] != [2]int{3, tt.expectedVersion} {
+				t.Errorf("Expected single edit from version 3 to %d, got %v", tt.expectedVersion, edits)
+			}
+
+			var response models.Notification
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Message != tt.expectedMessage || response.Version != tt.expectedVersion {
+				t.Errorf("Unexpected response: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestTemplateEndpoints tests POST /templates, GET /templates/{name} and preview
+func TestTemplateEndpoints(t *testing.T) {
+	welcome := models.Template{
+		Name:      "welcome",
+		Version:   2,
+		Format:    models.TemplateFormatHTML,
+		Subject:   "Hi {{.name}}",
+		Body:      "<p>Hello, {{.name}}!</p>",
+		Variables: []string{"name"},
+	}
+
+	tests := []struct {
+		name               string
+		method             string
+		target             string
+		requestBody        string
+		expectedStatusCode int
+		expectedBodyPart   string
+	}{
+		{
+			name:               "Create template",
+			method:             http.MethodPost,
+			target:             "/templates",
+			requestBody:        `{"name":"reminder","body":"Hello, {{.name}}","variables":["name"]}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedBodyPart:   `"format":"text"`,
+		},
+		{
+			name:               "Create template with broken syntax",
+			method:             http.MethodPost,
+			target:             "/templates",
+			requestBody:        `{"name":"reminder","body":"Hello, {{.name"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBodyPart:   "Invalid template",
+		},
+		{
+			name:               "Get latest version",
+			method:             http.MethodGet,
+			target:             "/templates/welcome",
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"version":2`,
+		},
+		{
+			name:               "Get unknown template",
+			method:             http.MethodGet,
+			target:             "/templates/unknown",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Preview escapes HTML",
+			method:             http.MethodPost,
+			target:             "/templates/welcome/preview",
+			requestBody:        `{"data":{"name":"<b>Bob</b>"}}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `Hello, \u0026lt;b\u0026gt;Bob`,
+		},
+		{
+			name:               "Preview without required variable",
+			method:             http.MethodPost,
+			target:             "/templates/welcome/preview",
+			requestBody:        `{"data":{}}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBodyPart:   "name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _, mockRedis := createMockDependencies()
+			mockRedis.GetTemplateFunc = func(ctx context.Context, name string, version int) (models.Template, error) {
+				if name != welcome.Name {
+					return models.Template{}, models.ErrNotFound
+				}
+				return welcome, nil
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /templates", func(w http.ResponseWriter, r *http.Request) {
+				CreateTemplate(ctx, mockRedis, w, r)
+			})
+			mux.HandleFunc("GET /templates/{name}", func(w http.ResponseWriter, r *http.Request) {
+				GetTemplate(ctx, mockRedis, w, r)
+			})
+			mux.HandleFunc("POST /templates/{name}/preview", func(w http.ResponseWriter, r *http.Request) {
+				PreviewTemplate(ctx, mockRedis, w, r)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBodyPart) {
+				t.Errorf("Expected body to contain '%s', got '%s'", tt.expectedBodyPart, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestCreateNotification_Template tests creation of notifications rendered from a template
+func TestCreateNotification_Template(t *testing.T) {
+	tests := []struct {
+		name               string
+		requestBody        string
+		expectedStatusCode int
+		expectedVersion    int
+	}{
+		{
+			name:               "Latest version is pinned",
+			requestBody:        `{"template":"welcome","data":{"name":"Alice"},"scheduled_at":1000}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedVersion:    3,
+		},
+		{
+			name:               "Required variable is missing",
+			requestBody:        `{"template":"welcome","data":{},"scheduled_at":1000}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Unknown template",
+			requestBody:        `{"template":"unknown","scheduled_at":1000}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Message together with template",
+			requestBody:        `{"template":"welcome","message":"Hi","data":{"name":"Alice"},"scheduled_at":1000}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Data without template",
+			requestBody:        `{"message":"Hi","data":{"name":"Alice"},"scheduled_at":1000}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+			mockRedis.GetTemplateFunc = func(ctx context.Context, name string, version int) (models.Template, error) {
+				if name != "welcome" {
+					return models.Template{}, models.ErrNotFound
+				}
+				return models.Template{Name: name, Version: 3, Format: models.TemplateFormatText, Body: "Hello, {{.name}}", Variables: []string{"name"}}, nil
+			}
+			var saved models.Notification
+			mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
+				saved = notif
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+			NotificationRequest(ctx, mockQueue, mockRedis, true)(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if saved.TemplateVersion != tt.expectedVersion {
+				t.Errorf("Expected template version %d, got %d", tt.expectedVersion, saved.TemplateVersion)
+			}
+		})
+	}
+}
+
+// TestCreateNotification_Fanout tests splitting of a notification into per-recipient deliveries
+func TestCreateNotification_Fanout(t *testing.T) {
+	tests := []struct {
+		name               string
+		requestBody        string
+		expectedStatusCode int
+		expectedChildren   int
+	}{
+		{
+			name:               "Fan-out to several channels",
+			requestBody:        `{"message":"Hi","scheduled_at":1000,"recipients":[{"channel":"email","address":"a@example.com"},{"channel":"telegram","address":"42"},{}]}`,
+			expectedStatusCode: http.StatusCreated,
+			expectedChildren:   3,
+		},
+		{
+			name:               "Address is required",
+			requestBody:        `{"message":"Hi","recipients":[{"channel":"email"}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Duplicate recipient",
+			requestBody:        `{"message":"Hi","recipients":[{"channel":"email","address":"a@example.com"},{"channel":"email","address":"a@example.com"}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Recipients together with channel",
+			requestBody:        `{"message":"Hi","channel":"email","recipients":[{"channel":"email","address":"a@example.com"}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+			var parent models.Notification
+			var children []models.Notification
+			mockRedis.SaveFanoutFunc = func(ctx context.Context, p models.Notification, c []models.Notification) error {
+				parent, children = p, c
+				return nil
+			}
+			mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
+				t.Errorf("Fan-out must not be saved as a single notification")
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+			NotificationRequest(ctx, mockQueue, mockRedis, true)(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if len(children) != tt.expectedChildren {
+				t.Fatalf("Expected %d children, got %d", tt.expectedChildren, len(children))
+			}
+			for i, child := range children {
+				if child.ParentUUID != parent.UUID || child.UUID != parent.Recipients[i].UUID || child.UUID == parent.UUID {
+					t.Errorf("Child %d is not linked to parent: %+v", i, child)
+				}
+				if child.Channel != parent.Recipients[i].Channel || child.Recipient != parent.Recipients[i].Address {
+					t.Errorf("Child %d has wrong recipient: %+v", i, child)
+				}
+				if child.Status != models.StatusPending || child.Message != "Hi" || !child.FireAt.Equal(parent.FireAt) {
+					t.Errorf("Child %d does not inherit the card: %+v", i, child)
+				}
+			}
+			if tt.expectedChildren > 0 && parent.Recipients[2].Channel != models.ChannelLog {
+				t.Errorf("Expected default log channel, got %q", parent.Recipients[2].Channel)
+			}
+		})
+	}
+}
+
+// TestFanoutEndpoints tests aggregate status and cancellation of a fan-out notification
+func TestFanoutEndpoints(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		childStatuses      []string
+		expectedStatusCode int
+		expectedStatus     string
+		expectedCancelled  []string
+	}{
+		{
+			name:               "All recipients pending",
+			method:             http.MethodGet,
+			childStatuses:      []string{models.StatusPending, models.StatusPending},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.StatusPending,
+		},
+		{
+			name:               "Delivery in progress",
+			method:             http.MethodGet,
+			childStatuses:      []string{models.StatusSent, models.StatusRetrying, models.StatusPending},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.StatusProcessing,
+		},
+		{
+			name:               "Partially sent",
+			method:             http.MethodGet,
+			childStatuses:      []string{models.StatusSent, models.StatusFailed},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.StatusPartial,
+		},
+		{
+			name:               "Cancel pending recipients",
+			method:             http.MethodDelete,
+			childStatuses:      []string{models.StatusSent, models.StatusPending, models.StatusPending},
+			expectedStatusCode: http.StatusOK,
+			expectedStatus:     models.StatusPartial,
+			expectedCancelled:  []string{"child-1", "child-2"},
+		},
+		{
+			name:               "Nothing to cancel",
+			method:             http.MethodDelete,
+			childStatuses:      []string{models.StatusSent, models.StatusProcessing},
+			expectedStatusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+
+			statuses := map[string]string{}
+			parent := models.Notification{UUID: "parent", Status: models.StatusPending}
+			for i, status := range tt.childStatuses {
+				id := fmt.Sprintf("child-%d", i)
+				statuses[id] = status
+				parent.Recipients = append(parent.Recipients, models.Recipient{UUID: id, Channel: models.ChannelLog})
+			}
+			mockRedis.GetFanoutFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+				return parent, nil
+			}
+			mockRedis.GetMessageFunc = func(ctx context.Context, uuid string) (models.Notification, error) {
+				status, ok := statuses[uuid]
+				if !ok {
+					return models.Notification{}, models.ErrNotFound
+				}
+				return models.Notification{UUID: uuid, Status: status, ParentUUID: "parent"}, nil
+			}
+			var cancelled []string
+			mockRedis.CancelMessageFunc = func(ctx context.Context, uuid string) error {
+				if statuses[uuid] != models.StatusPending {
+					if _, ok := statuses[uuid]; !ok {
+						return models.ErrNotFound
+					}
+					return models.ErrStatusConflict
+				}
+				statuses[uuid] = models.StatusCancelled
+				cancelled = append(cancelled, uuid)
+				return nil
+			}
+
+			req := httptest.NewRequest(tt.method, "/notify/parent", nil)
+			req.SetPathValue("id", "parent")
+			w := httptest.NewRecorder()
+			NotificationRequest(ctx, mockQueue, mockRedis, true)(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if fmt.Sprint(cancelled) != fmt.Sprint(tt.expectedCancelled) {
+				t.Errorf("Expected cancelled %v, got %v", tt.expectedCancelled, cancelled)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Status     string `json:"status"`
+				Recipients []struct {
+					UUID   string `json:"uuid"`
+					Status string `json:"status"`
+				} `json:"recipients"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Status != tt.expectedStatus {
+				t.Errorf("Expected aggregate status %q, got %q", tt.expectedStatus, response.Status)
+			}
+			if len(response.Recipients) != len(tt.childStatuses) {
+				t.Fatalf("Expected %d recipients, got %d", len(tt.childStatuses), len(response.Recipients))
+			}
+			for _, recipient := range response.Recipients {
+				if recipient.Status != statuses[recipient.UUID] {
+					t.Errorf("Recipient %s: expected status %q, got %q", recipient.UUID, statuses[recipient.UUID], recipient.Status)
+				}
+			}
+		})
+	}
+}
+
+// TestCreateNotifications_Batch tests POST /notify/batch per-item results
+func TestCreateNotifications_Batch(t *testing.T) {
+	ctx, mockQueue, mockRedis := createMockDependencies()
+
+	const (
+		first    = "0b7e2a64-5f0c-4c1e-9a3d-6f1e2d3c4b01"
+		existing = "0b7e2a64-5f0c-4c1e-9a3d-6f1e2d3c4b02"
+		broken   = "0b7e2a64-5f0c-4c1e-9a3d-6f1e2d3c4b03"
+	)
+	saved := map[string]bool{existing: true}
+	mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
+		if saved[notif.UUID] {
+			return models.ErrAlreadyExists
+		}
+		if notif.UUID == broken {
+			return errors.New("redis connection failed")
+		}
+		saved[notif.UUID] = true
+		return nil
+	}
+	templateLoads := 0
+	mockRedis.GetTemplateFunc = func(ctx context.Context, name string, version int) (models.Template, error) {
+		templateLoads++
+		return models.Template{Name: name, Version: 1, Format: models.TemplateFormatText, Body: "Hi {{.name}}", Variables: []string{"name"}}, nil
+	}
+	fanouts := 0
+	mockRedis.SaveFanoutFunc = func(ctx context.Context, parent models.Notification, children []models.Notification) error {
+		fanouts++
+		return nil
+	}
+
+	body := `{"notifications":[
+		{"uuid":"` + first + `","message":"One","scheduled_at":1000},
+		{"uuid":"` + existing + `","message":"Two","scheduled_at":1000},
+		{"uuid":"` + first + `","message":"Again","scheduled_at":1000},
+		{"message":"Bad","max_attempts":-1},
+		"not an object",
+		{"template":"welcome","data":{"name":"A"},"scheduled_at":1000},
+		{"template":"welcome","data":{"name":"B"},"scheduled_at":1000},
+		{"uuid":"` + broken + `","message":"Three","scheduled_at":1000},
+		{"message":"Fan-out","scheduled_at":1000,"recipients":[{"address":"a"},{"address":"b"}]},
+		{"uuid":"notify:outbox","message":"Reserved key","scheduled_at":1000}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/notify/batch", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	CreateNotifications(ctx, mockQueue, mockRedis, true, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response batchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expected := []string{
+		batchResultCreated, batchResultDuplicate, batchResultDuplicate, batchResultInvalid, batchResultInvalid,
+		batchResultCreated, batchResultCreated, batchResultError, batchResultCreated, batchResultInvalid,
+	}
+	if len(response.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(response.Results))
+	}
+	for i, result := range response.Results {
+		if result.Index != i || result.Result != expected[i] {
+			t.Errorf("Item %d: expected %q, got %+v", i, expected[i], result)
+		}
+	}
+	if response.Created != 4 {
+		t.Errorf("Expected 4 created, got %d", response.Created)
+	}
+	if templateLoads != 1 {
+		t.Errorf("Expected template to be loaded once per batch, got %d", templateLoads)
+	}
+	if fanouts != 1 {
+		t.Errorf("Expected 1 fan-out, got %d", fanouts)
+	}
+}
+
+// TestCreateNotifications_BatchLimits tests rejection of malformed batches
+func TestCreateNotifications_BatchLimits(t *testing.T) {
+	tooLarge := `{"notifications":[` + strings.Repeat(`{"message":"x"},`, maxBatchSize) + `{"message":"x"}]}`
+	tests := []struct {
+		name        string
+		requestBody string
+	}{
+		{name: "Invalid JSON", requestBody: `[`},
+		{name: "Empty batch", requestBody: `{"notifications":[]}`},
+		{name: "Batch is too large", requestBody: tooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, mockQueue, mockRedis := createMockDependencies()
+			mockRedis.SaveMessageFunc = func(ctx context.Context, notif models.Notification) error {
+				t.Errorf("Nothing must be saved")
+				return nil
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/notify/batch", bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+			CreateNotifications(ctx, mockQueue, mockRedis, true, w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+// TestPreferencesEndpoints tests quiet hours of recipients
+func TestPreferencesEndpoints(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		target             string
+		requestBody        string
+		expectedStatusCode int
+		expectedBodyPart   string
+	}{
+		{
+			name:               "Save overnight quiet hours",
+			method:             http.MethodPut,
+			target:             "/preferences",
+			requestBody:        `{"channel":"email","address":"new@example.com","timezone":"Europe/Moscow","quiet_hours":[{"start":"22:00","end":"08:00"}]}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"start":"22:00"`,
+		},
+		{
+			name:               "Default channel",
+			method:             http.MethodPut,
+			target:             "/preferences",
+			requestBody:        `{"address":"ops","timezone":"UTC"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"channel":"log"`,
+		},
+		{
+			name:               "Unknown timezone",
+			method:             http.MethodPut,
+			target:             "/preferences",
+			requestBody:        `{"channel":"email","address":"new@example.com","timezone":"Moscow"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBodyPart:   "timezone",
+		},
+		{
+			name:               "Invalid window",
+			method:             http.MethodPut,
+			target:             "/preferences",
+			requestBody:        `{"channel":"email","address":"new@example.com","timezone":"UTC","quiet_hours":[{"start":"10pm","end":"08:00"}]}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBodyPart:   "HH:MM",
+		},
+		{
+			name:               "Get preferences",
+			method:             http.MethodGet,
+			target:             "/preferences?channel=email&address=user@example.com",
+			expectedStatusCode: http.StatusOK,
+			expectedBodyPart:   `"timezone":"Asia/Tokyo"`,
+		},
+		{
+			name:               "Get unknown recipient",
+			method:             http.MethodGet,
+			target:             "/preferences?channel=telegram&address=user@example.com",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Get without address",
+			method:             http.MethodGet,
+			target:             "/preferences?channel=email",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Delete preferences",
+			method:             http.MethodDelete,
+			target:             "/preferences?channel=email&address=user@example.com",
+			expectedStatusCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _, mockRedis := createMockDependencies()
+			mockRedis.SavePreferences(ctx, models.Preferences{Channel: models.ChannelEmail, Address: "user@example.com", Timezone: "Asia/Tokyo"})
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /preferences", func(w http.ResponseWriter, r *http.Request) {
+				SavePreferences(ctx, mockRedis, w, r)
+			})
+			mux.HandleFunc("GET /preferences", func(w http.ResponseWriter, r *http.Request) {
+				GetPreferences(ctx, mockRedis, w, r)
+			})
+			mux.HandleFunc("DELETE /preferences", func(w http.ResponseWriter, r *http.Request) {
+				DeletePreferences(ctx, mockRedis, w, r)
+			})
+
+			req := httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.requestBody))
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBodyPart) {
+				t.Errorf("Expected body to contain '%s', got '%s'", tt.expectedBodyPart, w.Body.String())
+			}
+		})
+	}
+}
+
+// TestMetrics tests per-priority backlog exposition
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name               string
+		backlogErr         error
+		expectedStatusCode int
+		expectedLines      []string
+	}{
+		{
+			name:               "Backlog by priority",
+			expectedStatusCode: http.StatusOK,
+			expectedLines: []string{
+				`notify_backlog{priority="low"} 120`,
+				`notify_backlog{priority="normal"} 0`,
+				`notify_backlog{priority="urgent"} 2`,
+			},
+		},
+		{
+			name:               "Store failure",
+			backlogErr:         errors.New("redis connection failed"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _, mockRedis := createMockDependencies()
+			mockRedis.BacklogFunc = func(ctx context.Context, now time.Time) (map[string]int, error) {
+				return map[string]int{models.PriorityLow: 120, models.PriorityNormal: 0, models.PriorityUrgent: 2}, tt.backlogErr
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			w := httptest.NewRecorder()
+			Metrics(ctx, mockRedis, w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			for _, line := range tt.expectedLines {
+				if !strings.Contains(w.Body.String(), line+"\n") {
+					t.Errorf("Expected body to contain '%s', got '%s'", line, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// MockEventSource отдаёт заранее заданные события и закрывает канал, как отключённый подписчик
+type MockEventSource struct {
+	events []models.StatusEvent
+}
+
+func (m *MockEventSource) Subscribe() (<-chan models.StatusEvent, func()) {
+	ch := make(chan models.StatusEvent, len(m.events))
+	for _, event := range m.events {
+		ch <- event
+	}
+	close(ch)
+	return ch, func() {}
+}
+
+func TestStreamEvents(t *testing.T) {
+	event := func(id string, uuid string, tag string) models.StatusEvent {
+		return models.StatusEvent{ID: id, UUID: uuid, Tags: []string{tag}, StatusChange: models.StatusChange{Status: models.StatusSent}}
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		lastEventID        string
+		replayed           []models.StatusEvent
+		live               []models.StatusEvent
+		expectedStatusCode int
+		expectedIDs        []string
+	}{
+		{
+			name:               "Live events filtered by tag",
+			query:              "?tag=promo",
+			live:               []models.StatusEvent{event("1-0", "a", "promo"), event("2-0", "b", "other"), event("3-0", "c", "promo")},
+			expectedStatusCode: http.StatusOK,
+			expectedIDs:        []string{"1-0", "3-0"},
+		},
+		{
+			name:               "Filtered by notification",
+			query:              "?id=b",
+			live:               []models.StatusEvent{event("1-0", "a", "promo"), event("2-0", "b", "other")},
+			expectedStatusCode: http.StatusOK,
+			expectedIDs:        []string{"2-0"},
+		},
+		{
+			name:               "Resume after Last-Event-ID",
+			lastEventID:        "1-0",
+			replayed:           []models.StatusEvent{event("2-0", "b", "other"), event("3-0", "c", "promo")},
+			live:               []models.StatusEvent{event("3-0", "c", "promo"), event("4-0", "d", "promo")},
+			expectedStatusCode: http.StatusOK,
+			expectedIDs:        []string{"2-0", "3-0", "4-0"},
+		},
+		{
+			name:               "Invalid Last-Event-ID",
+			lastEventID:        "last",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _, mockRedis := createMockDependencies()
+			mockRedis.EventsAfterFunc = func(ctx context.Context, id string, limit int) ([]models.StatusEvent, error) {
+				if id != tt.lastEventID {
+					t.Errorf("Expected replay after %s, got %s", tt.lastEventID, id)
+				}
+				return tt.replayed, nil
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/notify/events"+tt.query, nil)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			w := httptest.NewRecorder()
+			StreamEvents(ctx, &MockEventSource{events: tt.live}, mockRedis, w, req)
+
+			if w.Code != tt.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "text/event-stream" {
+				t.Errorf("Expected text/event-stream, got %s", contentType)
+			}
+			var ids []string
+			for _, line := range strings.Split(w.Body.String(), "\n") {
+				if id, ok := strings.CutPrefix(line, "id: "); ok {
+					ids = append(ids, id)
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.expectedIDs) {
+				t.Errorf("Expected events %v, got %v", tt.expectedIDs, ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	// заголовок ответа, повторённого по ключу идемпотентности
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyTTL            = 24 * time.Hour
+	// idempotencyLease на сколько занимается ключ выполняющегося запроса: если процесс упал,
+	// не сохранив ответ, ключ освободится примерно через время выполнения запроса, а не через сутки
+	idempotencyLease        = time.Minute
+	maxIdempotencyKeyLength = 255
+)
+
+// withIdempotency выполняет create один раз на значение Idempotency-Key.
+// Успешный (2xx) ответ сохраняется на idempotencyTTL и возвращается на повторы того же запроса
+// (метод, путь и тело); тот же ключ с другим запросом – 422, пока первый выполняется – 409.
+// Неуспешный ответ освобождает ключ, чтобы запрос можно было повторить.
+func withIdempotency(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request, body []byte, create func(w http.ResponseWriter)) {
+	key := r.Header.Get(idempotencyHeader)
+	if key == "" {
+		create(w)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+		return
+	}
+
+	fingerprint := fingerprintOf(r, body)
+
+	record, reserved, err := rdb.ReserveIdempotencyKey(ctx, key, fingerprint, idempotencyLease)
+	if err != nil {
+		log.Printf("Failed to reserve idempotency key: %s", err)
+		http.Error(w, "Failed to check Idempotency-Key", http.StatusInternalServerError)
+		return
+	}
+	if !reserved {
+		replayIdempotent(w, record, fingerprint)
+		return
+	}
+
+	capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+	create(capture)
+
+	if capture.status < 200 || capture.status >= 300 {
+		if err := rdb.ReleaseIdempotencyKey(ctx, key); err != nil {
+			log.Printf("Failed to release idempotency key: %s", err)
+		}
+		return
+	}
+
+	record = models.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		StatusCode:  capture.status,
+		Location:    capture.Header().Get("Location"),
+		Body:        capture.body.Bytes(),
+	}
+	if err := rdb.SaveIdempotencyKey(ctx, key, record, idempotencyTTL); err != nil {
+		log.Printf("Failed to save idempotency key: %s", err)
+	}
+}
+
+// fingerprintOf отпечаток запроса: POST /notify и POST /notify/batch делят пространство ключей,
+// поэтому одинаковое тело на разные пути не должно совпадать
+func fingerprintOf(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replayIdempotent(w http.ResponseWriter, record models.IdempotencyRecord, fingerprint string) {
+	if record.Fingerprint != fingerprint {
+		http.Error(w, "Idempotency-Key is already used with a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	if record.StatusCode == 0 {
+		http.Error(w, "Request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	if record.Location != "" {
+		w.Header().Set("Location", record.Location)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	if _, err := w.Write(record.Body); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
+
+// captureWriter запоминает статус и тело ответа для сохранения по ключу идемпотентности
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(data []byte) (int, error) {
+	c.body.Write(data)
+	return c.ResponseWriter.Write(data)
+}
@@ -3,16 +3,48 @@ package handlers
 import (
 	"DelayedNotifier/internal/models"
 	"context"
+	"time"
 )
 
-// QueueProducer interface for RabbitMQ operations
+// QueueProducer interface for delayed delivery; implemented by any scheduler.Scheduler backend
 type QueueProducer interface {
 	SendMessage(notification models.Notification) error
+	CancelMessageDelay(uuid string) error
+	// MaxDelay потолок задержки планировщика; 0 – без ограничения
+	MaxDelay() time.Duration
+}
+
+// EventSource раздаёт новые события смены статуса (events.Broker); канал закрывается,
+// если подписчик отключён, отписка – второе возвращаемое значение
+type EventSource interface {
+	Subscribe() (<-chan models.StatusEvent, func())
 }
 
 // RedisStore interface for Redis operations
 type RedisStore interface {
 	SaveMessage(ctx context.Context, notif models.Notification) error
-	GetStatus(ctx context.Context, uuid string) (string, error)
-	DeleteMessage(ctx context.Context, uuid string) error
+	SaveMessages(ctx context.Context, notifs []models.Notification) ([]error, error)
+	GetMessage(ctx context.Context, uuid string) (models.Notification, error)
+	GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error)
+	ListMessages(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error)
+	Backlog(ctx context.Context, now time.Time) (map[string]int, error)
+	EventsAfter(ctx context.Context, id string, limit int) ([]models.StatusEvent, error)
+	CancelMessage(ctx context.Context, uuid string) error
+	SaveStatus(ctx context.Context, uuid string, status string) error
+	ListDead(ctx context.Context, limit int) ([]models.DeadLetter, error)
+	ReviveDead(ctx context.Context, uuid string) (models.Notification, error)
+	MarkDead(ctx context.Context, uuid string, at time.Time) error
+	TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error)
+	EditMessage(ctx context.Context, notif models.Notification, version int, newVersion int) error
+	ResumeSeries(ctx context.Context, uuid string, version int, fireAt time.Time) error
+	ReserveIdempotencyKey(ctx context.Context, key string, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error)
+	SaveIdempotencyKey(ctx context.Context, key string, record models.IdempotencyRecord, ttl time.Duration) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+	SaveTemplate(ctx context.Context, template models.Template) (models.Template, error)
+	GetTemplate(ctx context.Context, name string, version int) (models.Template, error)
+	SaveFanout(ctx context.Context, parent models.Notification, children []models.Notification) error
+	GetFanout(ctx context.Context, uuid string) (models.Notification, error)
+	SavePreferences(ctx context.Context, prefs models.Preferences) error
+	GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error)
+	DeletePreferences(ctx context.Context, channel string, address string) error
 }
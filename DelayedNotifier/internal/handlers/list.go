@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+type listResponse struct {
+	Items      []models.Notification `json:"items"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// ListNotifications GET /notify?status=&from=&to=&recipient=&tag=&limit=&cursor= – уведомления
+// по возрастанию fire_at; from и to в RFC 3339, cursor берётся из next_cursor предыдущей страницы
+func ListNotifications(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.ListFilter{
+		Status:    query.Get("status"),
+		Recipient: query.Get("recipient"),
+		Tag:       query.Get("tag"),
+		Limit:     defaultListLimit,
+		Cursor:    query.Get("cursor"),
+	}
+
+	if filter.Status != "" && !slices.Contains(models.Statuses, filter.Status) {
+		http.Error(w, "Unknown status "+filter.Status, http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxListLimit {
+			http.Error(w, "limit must be an integer between 1 and "+strconv.Itoa(maxListLimit), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = parsed
+	}
+	for name, bound := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, name+" must be RFC 3339", http.StatusBadRequest)
+			return
+		}
+		*bound = parsed
+	}
+
+	items, next, err := rdb.ListMessages(ctx, filter)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to list notifications: %s", err)
+		http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Items: items, NextCursor: next})
+}
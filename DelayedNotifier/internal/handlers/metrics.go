@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Metrics GET /metrics – метрики сервиса в текстовом формате Prometheus:
+// notify_backlog – наступившие, но ещё не доставленные уведомления по приоритету
+func Metrics(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	backlog, err := rdb.Backlog(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to count backlog: %s", err)
+		http.Error(w, "Failed to collect metrics", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP notify_backlog Due notifications waiting for delivery.")
+	fmt.Fprintln(w, "# TYPE notify_backlog gauge")
+	for _, priority := range models.Priorities {
+		fmt.Fprintf(w, "notify_backlog{priority=%q} %d\n", priority, backlog[priority])
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// editRequest тело PATCH /notify/{id}; отсутствующие поля не меняются
+type editRequest struct {
+	Message     *string `json:"message"`
+	Subject     *string `json:"subject"`
+	ScheduledAt *int64  `json:"scheduled_at"`
+	SendAt      *string `json:"send_at"`
+	Timezone    *string `json:"timezone"`
+	AllowPast   bool    `json:"allow_past"`
+}
+
+func (e editRequest) reschedules() bool {
+	return e.ScheduledAt != nil || e.SendAt != nil
+}
+
+// EditNotification PATCH /notify/{id} – меняет текст и/или время отправки ожидающего уведомления.
+// Сообщение в delayedExchange изменить нельзя, поэтому перенос увеличивает версию расписания
+// и в той же операции хранилища ставит уведомление в outbox: новое сообщение публикует
+// outbox relay, старое worker отбросит по версии.
+func EditNotification(ctx context.Context, qp QueueProducer, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	uuid := r.PathValue("id")
+	if uuid == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	var req editRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Message == nil && req.Subject == nil && !req.reschedules() {
+		http.Error(w, "Nothing to change: expected message, subject, scheduled_at or send_at", http.StatusBadRequest)
+		return
+	}
+	if req.Timezone != nil && req.SendAt == nil {
+		http.Error(w, "timezone can only be changed together with send_at", http.StatusBadRequest)
+		return
+	}
+
+	current, err := rdb.GetMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get notification: %s", err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return
+	}
+	if current.Status != models.StatusPending {
+		http.Error(w, "Only pending notifications can be changed: status is "+current.Status, http.StatusConflict)
+		return
+	}
+
+	if req.Message != nil && current.Template != "" {
+		http.Error(w, "Message of a templated notification is rendered from template "+current.Template, http.StatusBadRequest)
+		return
+	}
+
+	updated := current
+	if req.Message != nil {
+		updated.Message = *req.Message
+	}
+	if req.Subject != nil {
+		updated.Subject = *req.Subject
+	}
+
+	version := current.Version
+	if req.reschedules() {
+		if current.Cron != "" {
+			http.Error(w, "Schedule of a series is defined by cron; use pause and resume", http.StatusBadRequest)
+			return
+		}
+
+		updated.ScheduledAt, updated.SendAt, updated.AllowPast = 0, "", req.AllowPast
+		if req.ScheduledAt != nil {
+			updated.ScheduledAt = *req.ScheduledAt
+		}
+		if req.SendAt != nil {
+			updated.SendAt = *req.SendAt
+		}
+		if req.Timezone != nil {
+			updated.Timezone = *req.Timezone
+		}
+		if err := resolveSchedule(&updated, time.Now(), qp.MaxDelay()); err != nil {
+			http.Error(w, "Invalid schedule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		version++
+	}
+
+	err = rdb.EditMessage(ctx, updated, current.Version, version)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrStatusConflict) {
+		http.Error(w, "Notification can not be changed: "+err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to edit notification: %s", err)
+		http.Error(w, "Failed to edit notification", http.StatusInternalServerError)
+		return
+	}
+	updated.Version = version
+
+	writeJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/quiethours"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// SavePreferences PUT /preferences – задаёт тихие часы получателя канала (channel по умолчанию log),
+// заменяя прежние настройки. Несрочные уведомления в тихие часы worker откладывает до их конца.
+func SavePreferences(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	var prefs models.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if prefs.Channel == "" {
+		prefs.Channel = models.ChannelLog
+	}
+	if prefs.QuietHours == nil {
+		prefs.QuietHours = []models.QuietWindow{}
+	}
+	if err := quiethours.Validate(prefs); err != nil {
+		http.Error(w, "Invalid preferences: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	prefs.UpdatedAt = time.Now()
+
+	if err := rdb.SavePreferences(ctx, prefs); err != nil {
+		log.Printf("Failed to save preferences: %s", err)
+		http.Error(w, "Failed to save preferences", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// GetPreferences GET /preferences?channel=&address= – настройки получателя; 404 – не заданы
+func GetPreferences(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	channel, address, ok := recipientQuery(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := rdb.GetPreferences(ctx, channel, address)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Preferences of "+channel+" recipient "+address+" are not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get preferences: %s", err)
+		http.Error(w, "Failed to get preferences", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, prefs)
+}
+
+// DeletePreferences DELETE /preferences?channel=&address= – снимает тихие часы получателя
+func DeletePreferences(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	channel, address, ok := recipientQuery(w, r)
+	if !ok {
+		return
+	}
+
+	err := rdb.DeletePreferences(ctx, channel, address)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Preferences of "+channel+" recipient "+address+" are not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete preferences: %s", err)
+		http.Error(w, "Failed to delete preferences", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// recipientQuery читает получателя из параметров запроса; channel по умолчанию log
+func recipientQuery(w http.ResponseWriter, r *http.Request) (channel string, address string, ok bool) {
+	query := r.URL.Query()
+	channel, address = query.Get("channel"), query.Get("address")
+	if channel == "" {
+		channel = models.ChannelLog
+	}
+	if address == "" {
+		http.Error(w, "address is required", http.StatusBadRequest)
+		return "", "", false
+	}
+	return channel, address, true
+}
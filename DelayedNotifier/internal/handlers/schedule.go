@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/cron"
+	"DelayedNotifier/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// форматы send_at без смещения, которые интерпретируются в поясе timezone
+var wallClockLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// resolveSchedule вычисляет абсолютное время отправки (FireAt) и задержку публикации (ScheduledAt).
+// send_at принимается в RFC 3339 или как время без смещения вместе с IANA timezone;
+// без send_at scheduled_at остаётся относительной задержкой в миллисекундах.
+// Для повторяющегося уведомления (cron) вычисляется первое срабатывание серии.
+// maxDelay – потолок задержки планировщика (QueueProducer.MaxDelay), 0 – только models.MaxScheduleDelay.
+func resolveSchedule(notification *models.Notification, now time.Time, maxDelay time.Duration) error {
+	if notification.Cron != "" {
+		return resolveSeries(notification, now, maxDelay)
+	}
+
+	if notification.SendAt == "" {
+		if notification.ScheduledAt < 0 {
+			return errors.New("scheduled_at must not be negative")
+		}
+		// сравнение в миллисекундах: умножение слишком большого scheduled_at переполнилось бы
+		if limit := delayLimit(maxDelay); notification.ScheduledAt > limit.Milliseconds() {
+			return fmt.Errorf("scheduled_at must not exceed %d ms", limit.Milliseconds())
+		}
+		notification.FireAt = now.Add(time.Duration(notification.ScheduledAt) * time.Millisecond)
+		return nil
+	}
+
+	if notification.ScheduledAt != 0 {
+		return errors.New("send_at and scheduled_at are mutually exclusive")
+	}
+
+	fireAt, err := parseSendAt(notification.SendAt, notification.Timezone)
+	if err != nil {
+		return err
+	}
+
+	delay := fireAt.Sub(now)
+	if delay < 0 {
+		if !notification.AllowPast {
+			return errors.New("send_at is in the past")
+		}
+		delay = 0
+	}
+	if exceedsDelay(delay, maxDelay) {
+		return fmt.Errorf("send_at must be within %s from now", delayLimit(maxDelay).Round(time.Hour))
+	}
+
+	notification.FireAt = fireAt
+	notification.ScheduledAt = delay.Milliseconds()
+	return nil
+}
+
+// resolveSeries находит первое срабатывание серии; send_at задаёт её начало
+func resolveSeries(notification *models.Notification, now time.Time, maxDelay time.Duration) error {
+	if notification.ScheduledAt != 0 {
+		return errors.New("cron and scheduled_at are mutually exclusive")
+	}
+	if notification.MaxOccurrences < 0 {
+		return errors.New("max_occurrences must not be negative")
+	}
+
+	after := now
+	if notification.SendAt != "" {
+		start, err := parseSendAt(notification.SendAt, notification.Timezone)
+		if err != nil {
+			return err
+		}
+		// срабатывание ровно в момент начала серии тоже считается
+		if start.After(now) {
+			after = start.Add(-time.Nanosecond)
+		}
+	}
+
+	fireAt, ok, err := cron.NextOccurrence(*notification, after)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("series has no occurrences")
+	}
+
+	delay := fireAt.Sub(now)
+	if exceedsDelay(delay, maxDelay) {
+		return fmt.Errorf("first occurrence must be within %s from now", delayLimit(maxDelay).Round(time.Hour))
+	}
+
+	notification.FireAt = fireAt
+	notification.ScheduledAt = delay.Milliseconds()
+	return nil
+}
+
+// exceedsDelay сообщает, что задержку delay планировщик с потолком maxDelay не примет
+func exceedsDelay(delay time.Duration, maxDelay time.Duration) bool {
+	return delay > delayLimit(maxDelay)
+}
+
+// delayLimit наибольшая допустимая задержка: потолок планировщика, но не больше models.MaxScheduleDelay
+func delayLimit(maxDelay time.Duration) time.Duration {
+	if maxDelay > 0 && maxDelay < models.MaxScheduleDelay {
+		return maxDelay
+	}
+	return models.MaxScheduleDelay
+}
+
+func parseSendAt(sendAt, timezone string) (time.Time, error) {
+	if fireAt, err := time.Parse(time.RFC3339, sendAt); err == nil {
+		return fireAt, nil
+	}
+
+	if timezone == "" {
+		return time.Time{}, errors.New("send_at must be RFC 3339 or be accompanied by timezone")
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	for _, layout := range wallClockLayouts {
+		if fireAt, err := time.ParseInLocation(layout, sendAt, location); err == nil {
+			return fireAt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid send_at %q", sendAt)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/cron"
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultOccurrencesLimit = 10
+	maxOccurrencesLimit     = 1000
+)
+
+type occurrencesResponse struct {
+	UUID        string      `json:"uuid"`
+	Cron        string      `json:"cron"`
+	Timezone    string      `json:"timezone,omitempty"`
+	Status      string      `json:"status"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+// ListOccurrences GET /notify/{id}/occurrences?limit=N – ближайшие срабатывания серии
+func ListOccurrences(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	limit := defaultOccurrencesLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxOccurrencesLimit {
+			http.Error(w, "limit must be an integer between 1 and "+strconv.Itoa(maxOccurrencesLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	notification, ok := getSeries(ctx, rdb, w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	response := occurrencesResponse{
+		UUID:        notification.UUID,
+		Cron:        notification.Cron,
+		Timezone:    notification.Timezone,
+		Status:      notification.Status,
+		Occurrences: []time.Time{},
+	}
+	// у завершённой или отменённой серии срабатываний больше нет
+	switch notification.Status {
+	case models.StatusSent, models.StatusFailed, models.StatusCancelled:
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	after := time.Now()
+	// запланированное срабатывание ещё не наступило и входит в список
+	if notification.FireAt.After(after) {
+		after = notification.FireAt.Add(-time.Nanosecond)
+	}
+	upcoming, err := cron.Upcoming(notification, after, limit)
+	if err != nil {
+		log.Printf("Failed to compute occurrences of %s: %s", notification.UUID, err)
+		http.Error(w, "Failed to compute occurrences: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Occurrences = upcoming
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// PauseSeries POST /notify/{id}/pause – приостанавливает серию; запланированное
+// срабатывание будет пропущено worker-ом
+func PauseSeries(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	notification, ok := getSeries(ctx, rdb, w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	_, err := rdb.TransitionStatus(ctx, notification.UUID, models.StatusPaused, models.StatusPending, models.StatusRetrying)
+	if !writeTransitionError(w, err, "paused") {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"uuid": notification.UUID, "status": models.StatusPaused})
+}
+
+// ResumeSeries POST /notify/{id}/resume – возобновляет серию со следующего срабатывания после текущего момента
+func ResumeSeries(ctx context.Context, qp QueueProducer, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	notification, ok := getSeries(ctx, rdb, w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+	if notification.Status != models.StatusPaused {
+		http.Error(w, "Series is not paused: status is "+notification.Status, http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	fireAt, ok, err := cron.NextOccurrence(notification, now)
+	if err != nil {
+		http.Error(w, "Failed to compute next occurrence: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Series has no upcoming occurrences", http.StatusConflict)
+		return
+	}
+	if exceedsDelay(fireAt.Sub(now), qp.MaxDelay()) {
+		http.Error(w, "Next occurrence exceeds the delay limit", http.StatusConflict)
+		return
+	}
+
+	// новая версия расписания: сообщение, опубликованное до паузы, будет отброшено;
+	// следующее срабатывание публикует outbox relay
+	err = rdb.ResumeSeries(ctx, notification.UUID, notification.Version, fireAt)
+	if !writeTransitionError(w, err, "resumed") {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"uuid":    notification.UUID,
+		"status":  models.StatusPending,
+		"fire_at": fireAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// getSeries загружает повторяющееся уведомление и пишет ошибку в ответ, если его нет
+func getSeries(ctx context.Context, rdb RedisStore, w http.ResponseWriter, uuid string) (models.Notification, bool) {
+	if uuid == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return models.Notification{}, false
+	}
+
+	notification, err := rdb.GetMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+		return models.Notification{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to get notification %s: %s", uuid, err)
+		http.Error(w, "Failed to get notification", http.StatusInternalServerError)
+		return models.Notification{}, false
+	}
+	if notification.Cron == "" {
+		http.Error(w, "Notification is not recurring", http.StatusBadRequest)
+		return models.Notification{}, false
+	}
+	return notification, true
+}
+
+// writeTransitionError отвечает 404/409/500 на ошибку смены статуса; false – ответ уже записан
+func writeTransitionError(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, models.ErrNotFound):
+		http.Error(w, "Notification is not found", http.StatusNotFound)
+	case errors.Is(err, models.ErrStatusConflict):
+		http.Error(w, "Series can not be "+action+": "+err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Failed to change series status: %s", err)
+		http.Error(w, "Failed to change series status", http.StatusInternalServerError)
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/templates"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errInvalidTemplate шаблон или данные уведомления не прошли проверку
+var errInvalidTemplate = errors.New("invalid template")
+
+// previewRequest тело POST /templates/{name}/preview; version 0 – последняя версия
+type previewRequest struct {
+	Version int            `json:"version"`
+	Data    map[string]any `json:"data"`
+}
+
+type previewResponse struct {
+	Version int    `json:"version"`
+	Format  string `json:"format"`
+	Subject string `json:"subject,omitempty"`
+	Message string `json:"message"`
+}
+
+// CreateTemplate POST /templates – регистрирует шаблон; повторная регистрация имени создаёт новую версию
+func CreateTemplate(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	var template models.Template
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if template.Format == "" {
+		template.Format = models.TemplateFormatText
+	}
+	if err := templates.Validate(template); err != nil {
+		http.Error(w, "Invalid template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	template.CreatedAt = time.Now()
+
+	template, err := rdb.SaveTemplate(ctx, template)
+	if err != nil {
+		log.Printf("Failed to save template: %s", err)
+		http.Error(w, "Failed to save template", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/templates/"+template.Name)
+	writeJSON(w, http.StatusCreated, template)
+}
+
+// GetTemplate GET /templates/{name}?version=N – версия шаблона, по умолчанию последняя
+func GetTemplate(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if value := r.URL.Query().Get("version"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "version must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		version = parsed
+	}
+
+	template, ok := loadTemplate(ctx, rdb, w, r.PathValue("name"), version)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+// PreviewTemplate POST /templates/{name}/preview – рендер шаблона с данными без создания уведомления
+func PreviewTemplate(ctx context.Context, rdb RedisStore, w http.ResponseWriter, r *http.Request) {
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Failed to unmarshal JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Version < 0 {
+		http.Error(w, "version must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	template, ok := loadTemplate(ctx, rdb, w, r.PathValue("name"), req.Version)
+	if !ok {
+		return
+	}
+	subject, message, err := templates.Render(template, req.Data)
+	if err != nil {
+		http.Error(w, "Failed to render template: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, previewResponse{
+		Version: template.Version,
+		Format:  template.Format,
+		Subject: subject,
+		Message: message,
+	})
+}
+
+// loadTemplate загружает шаблон или пишет ответ об ошибке
+func loadTemplate(ctx context.Context, rdb RedisStore, w http.ResponseWriter, name string, version int) (models.Template, bool) {
+	template, err := rdb.GetTemplate(ctx, name, version)
+	if errors.Is(err, models.ErrNotFound) {
+		http.Error(w, "Template is not found", http.StatusNotFound)
+		return models.Template{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to get template: %s", err)
+		http.Error(w, "Failed to get template", http.StatusInternalServerError)
+		return models.Template{}, false
+	}
+	return template, true
+}
+
+// templateStore источник шаблонов для проверки уведомлений
+type templateStore interface {
+	GetTemplate(ctx context.Context, name string, version int) (models.Template, error)
+}
+
+// resolveTemplate закрепляет за уведомлением версию шаблона и проверяет, что данных хватает для рендера
+func resolveTemplate(ctx context.Context, rdb templateStore, notification *models.Notification) error {
+	if notification.Template == "" {
+		if len(notification.Data) > 0 {
+			return fmt.Errorf("%w: data requires template", errInvalidTemplate)
+		}
+		return nil
+	}
+	if notification.Message != "" {
+		return fmt.Errorf("%w: message and template are mutually exclusive", errInvalidTemplate)
+	}
+	if notification.TemplateVersion < 0 {
+		return fmt.Errorf("%w: template_version must not be negative", errInvalidTemplate)
+	}
+
+	template, err := rdb.GetTemplate(ctx, notification.Template, notification.TemplateVersion)
+	if err != nil {
+		return err
+	}
+	if _, _, err := templates.Render(template, notification.Data); err != nil {
+		return fmt.Errorf("%w: %s", errInvalidTemplate, err)
+	}
+	notification.TemplateVersion = template.Version
+	return nil
+}
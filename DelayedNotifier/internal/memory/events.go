@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/events"
+	"DelayedNotifier/internal/models"
+	"context"
+	"slices"
+	"time"
+)
+
+const (
+	// eventsLength сколько последних событий хранится для возобновления, как MAXLEN потока redisdb
+	eventsLength = 10000
+	// eventStreamBuffer подписчик, пропустивший столько событий, отключается, как клиент Pub/Sub
+	eventStreamBuffer = 1024
+)
+
+// publishEvent добавляет смену статуса записи в поток событий и раздаёт её подписчикам;
+// вызывается под s.mu
+func (s *Store) publishEvent(r *record, change models.StatusChange) {
+	s.lastEventID = s.lastEventID.Next(uint64(time.Now().UnixMilli()))
+	event := models.StatusEvent{
+		ID:           s.lastEventID.String(),
+		UUID:         r.notification.UUID,
+		ParentUUID:   r.notification.ParentUUID,
+		StatusChange: change,
+		Tags:         slices.Clone(r.notification.Tags),
+		Tenant:       r.notification.Tenant,
+	}
+	s.events = append(s.events, event)
+	if len(s.events) > eventsLength {
+		s.events = slices.Delete(s.events, 0, len(s.events)-eventsLength)
+	}
+
+	for ch := range s.eventStreams {
+		select {
+		case ch <- event:
+		default:
+			delete(s.eventStreams, ch)
+			close(ch)
+		}
+	}
+}
+
+// SubscribeEvents подписывается на новые события смены статуса;
+// канал закрывается при отмене ctx или если подписчик не успевает читать
+func (s *Store) SubscribeEvents(ctx context.Context) (<-chan models.StatusEvent, error) {
+	ch := make(chan models.StatusEvent, eventStreamBuffer)
+	s.mu.Lock()
+	s.eventStreams[ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.eventStreams[ch]; ok {
+			delete(s.eventStreams, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+// EventsAfter возвращает до limit событий, появившихся после события id
+func (s *Store) EventsAfter(ctx context.Context, id string, limit int) ([]models.StatusEvent, error) {
+	after, err := events.ParseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []models.StatusEvent
+	for _, event := range s.events {
+		if len(result) == limit {
+			break
+		}
+		// id событий в s.events всегда корректны
+		if eventID, _ := events.ParseID(event.ID); eventID.After(after) {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/worker"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// HandlerFunc обрабатывает одно сообщение: UUID уведомления и версию его расписания
+type HandlerFunc func(ctx context.Context, uuid string, version int) error
+
+type message struct {
+	uuid        string
+	version     int
+	priority    int // models.PriorityLevel уведомления
+	redelivered bool
+}
+
+// Queue планировщик и очередь в памяти процесса вместо RabbitMQ: отложенные сообщения
+// держит колесо таймеров, наступившие обрабатываются в Run с той же семантикой, что и
+// rabbitMQ.Consumer: worker.ErrDeadLetter отправляет сообщение в dead-letter обработчик сразу,
+// другая ошибка повторяет доставку один раз. Наступившие сообщения ждут обработчиков
+// в очередях по приоритету: обработчик берёт сообщение из самой приоритетной непустой.
+// Сообщения теряются при перезапуске, их восстанавливает outbox reconciler.
+type Queue struct {
+	wheel *timingWheel
+
+	mu sync.Mutex
+	// lanes наступившие сообщения, индекс – models.PriorityLevel
+	lanes [][]message
+	size  int
+	// ready сигнал обработчикам, что в lanes есть сообщения
+	ready chan struct{}
+}
+
+func NewQueue(tick time.Duration) *Queue {
+	return &Queue{
+		wheel: newTimingWheel(tick),
+		lanes: make([][]message, len(models.Priorities)),
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// SendMessage откладывает сообщение на notification.ScheduledAt мс
+func (q *Queue) SendMessage(notification models.Notification) error {
+	if notification.ScheduledAt > models.MaxScheduleDelay.Milliseconds() {
+		log.Printf("Failed to schedule a message %s: delay %d ms exceeds the limit", notification.UUID, notification.ScheduledAt)
+		return models.ErrDelayTooLong
+	}
+	m := message{uuid: notification.UUID, version: notification.Version, priority: models.PriorityLevel(notification.Priority)}
+	if !q.wheel.add(m, time.Duration(notification.ScheduledAt)*time.Millisecond) {
+		q.push(m)
+	}
+	return nil
+}
+
+// CancelMessageDelay как и для delayed exchange только фиксирует отмену:
+// отложенное сообщение дойдёт до обработчика, который пропустит отменённое уведомление
+func (q *Queue) CancelMessageDelay(messageId string) error {
+	log.Printf("Notification %s is cancelled, its delayed message will be skipped by consumer", messageId)
+	return nil
+}
+
+// MaxDelay колесо таймеров откладывает сообщение на любое число оборотов,
+// задержку ограничивает только models.MaxScheduleDelay
+func (q *Queue) MaxDelay() time.Duration {
+	return 0
+}
+
+// Len число отложенных сообщений, ещё не переданных обработчикам
+func (q *Queue) Len() int {
+	return q.wheel.len()
+}
+
+// push передаёт сообщение обработчикам, не блокируя отправителя
+func (q *Queue) push(m message) {
+	q.mu.Lock()
+	q.lanes[m.priority] = append(q.lanes[m.priority], m)
+	q.size++
+	q.mu.Unlock()
+	q.signal()
+}
+
+// signal будит один обработчик; сигнал не теряется, пока его никто не забрал
+func (q *Queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop ждёт наступившее сообщение с наибольшим приоритетом; false – ctx отменён
+func (q *Queue) pop(ctx context.Context) (message, bool) {
+	for {
+		q.mu.Lock()
+		for level := len(q.lanes) - 1; level >= 0; level-- {
+			if len(q.lanes[level]) == 0 {
+				continue
+			}
+			m := q.lanes[level][0]
+			q.lanes[level] = q.lanes[level][1:]
+			q.size--
+			left := q.size
+			q.mu.Unlock()
+			// остальные сообщения достанутся следующему обработчику
+			if left > 0 {
+				q.signal()
+			}
+			return m, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return message{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+// Run крутит колесо таймеров и обрабатывает наступившие сообщения в workers горутинах
+// до отмены ctx; окончательно неудачные сообщения передаются handleDead.
+func (q *Queue) Run(ctx context.Context, workers int, handle, handleDead HandlerFunc) error {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go q.consume(ctx, handle, handleDead)
+	}
+
+	ticker := time.NewTicker(q.wheel.tick)
+	defer ticker.Stop()
+
+	// колесо догоняет реальное время, если тики задержались
+	start := time.Now()
+	advanced := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			for target := int(now.Sub(start) / q.wheel.tick); advanced < target; advanced++ {
+				for _, m := range q.wheel.advance() {
+					q.push(m)
+				}
+			}
+		}
+	}
+}
+
+func (q *Queue) consume(ctx context.Context, handle, handleDead HandlerFunc) {
+	for {
+		m, ok := q.pop(ctx)
+		if !ok {
+			return
+		}
+		err := handle(ctx, m.uuid, m.version)
+		if err == nil {
+			continue
+		}
+		log.Printf("Failed to handle message %s: %s", m.uuid, err)
+
+		if !m.redelivered && !errors.Is(err, worker.ErrDeadLetter) {
+			m.redelivered = true
+			q.push(m)
+			continue
+		}
+		if err := handleDead(ctx, m.uuid, m.version); err != nil {
+			log.Printf("Failed to handle dead message %s: %s", m.uuid, err)
+		}
+	}
+}
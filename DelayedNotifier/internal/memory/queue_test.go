@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQueue_Priority(t *testing.T) {
+	q := NewQueue(10 * time.Millisecond)
+	for _, n := range []struct {
+		uuid     string
+		priority string
+	}{
+		{"low-1", models.PriorityLow},
+		{"normal-1", ""},
+		{"urgent-1", models.PriorityUrgent},
+		{"low-2", models.PriorityLow},
+		{"high-1", models.PriorityHigh},
+		{"urgent-2", models.PriorityUrgent},
+	} {
+		notification := models.Notification{UUID: n.uuid}
+		notification.Priority = n.priority
+		if err := q.SendMessage(notification); err != nil {
+			t.Fatalf("Failed to send %s: %v", n.uuid, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var order []string
+	for range 6 {
+		m, ok := q.pop(ctx)
+		if !ok {
+			t.Fatal("Expected a message")
+		}
+		order = append(order, m.uuid)
+	}
+
+	// внутри одного приоритета порядок FIFO
+	expected := []string{"urgent-1", "urgent-2", "high-1", "normal-1", "low-1", "low-2"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("Expected order %v, got %v", expected, order)
+	}
+
+	cancel()
+	if _, ok := q.pop(ctx); ok {
+		t.Error("Expected empty queue to stop on cancelled context")
+	}
+}
+
+func TestQueue_SendMessageDelayLimit(t *testing.T) {
+	q := NewQueue(10 * time.Millisecond)
+	n := models.Notification{UUID: "far", NotificationCard: models.NotificationCard{ScheduledAt: 9300000000000}}
+	if err := q.SendMessage(n); !errors.Is(err, models.ErrDelayTooLong) {
+		t.Errorf("Expected ErrDelayTooLong, got %v", err)
+	}
+	if q.Len() != 0 {
+		t.Errorf("Expected nothing scheduled, got %d", q.Len())
+	}
+}
@@ -0,0 +1,813 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/events"
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/ratelimit"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// record уведомление вместе с историей, как его хранит redisdb в нескольких ключах
+type record struct {
+	notification models.Notification
+	history      []models.StatusChange
+	attempts     []models.Attempt
+	// enqueuedAt время последней постановки в outbox через EnqueueOutbox
+	enqueuedAt time.Time
+}
+
+type idempotencyEntry struct {
+	record  models.IdempotencyRecord
+	expires time.Time
+}
+
+// Store хранилище уведомлений в памяти процесса с той же семантикой, что и redisdb.RedisConnection.
+// Данные теряются при перезапуске: хранилище предназначено для локального запуска и тестов.
+type Store struct {
+	mu          sync.Mutex
+	records     map[string]*record
+	dead        map[string]time.Time
+	outbox      map[string]time.Time
+	idempotency map[string]idempotencyEntry
+	templates   map[string][]models.Template
+	fanouts     map[string]models.Notification
+	buckets     map[string]bucketState
+	preferences map[string]models.Preferences
+	// callbacks очередь событий для callback_url: событие в JSON – время следующей попытки
+	callbacks map[string]time.Time
+	// events поток событий смены статуса, как поток redisdb, и его подписчики
+	events       []models.StatusEvent
+	lastEventID  events.ID
+	eventStreams map[chan models.StatusEvent]struct{}
+}
+
+// bucketState число токенов корзины ограничения частоты на момент at; в отличие от Redis
+// корзины не истекают – хранилище в памяти рассчитано на локальный запуск
+type bucketState struct {
+	tokens float64
+	at     time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		records:     map[string]*record{},
+		dead:        map[string]time.Time{},
+		outbox:      map[string]time.Time{},
+		idempotency: map[string]idempotencyEntry{},
+		templates:   map[string][]models.Template{},
+		fanouts:     map[string]models.Notification{},
+		buckets:     map[string]bucketState{},
+		preferences: map[string]models.Preferences{},
+		callbacks:   map[string]time.Time{},
+
+		eventStreams: map[chan models.StatusEvent]struct{}{},
+	}
+}
+
+func (s *Store) Close() {}
+
+// clone копирует уведомление без полей, которые redisdb не сохраняет
+func clone(notif models.Notification) models.Notification {
+	notif.SendAt = ""
+	notif.AllowPast = false
+	notif.Format = ""
+	notif.Tags = slices.Clone(notif.Tags)
+	notif.Data = maps.Clone(notif.Data)
+	notif.Recipients = slices.Clone(notif.Recipients)
+	return notif
+}
+
+func (r *record) setStatus(change models.StatusChange) {
+	r.notification.Status = change.Status
+	r.history = append(r.history, change)
+}
+
+// setStatus меняет статус записи и, как redisdb, публикует событие смены статуса
+// и ставит его в очередь callback-ов
+func (s *Store) setStatus(r *record, status string) {
+	change := models.StatusChange{Status: status, At: time.Now()}
+	r.setStatus(change)
+	s.publishEvent(r, change)
+	if r.notification.CallbackURL == "" || !slices.Contains(models.CallbackStatuses, status) {
+		return
+	}
+	now := time.Now()
+	data, _ := json.Marshal(models.CallbackEvent{ID: uuid.NewString(), UUID: r.notification.UUID, Status: status, At: now})
+	s.callbacks[string(data)] = now
+}
+
+// SaveMessage сохраняет новое уведомление и ставит его в outbox;
+// существующее не перезаписывается и возвращается models.ErrAlreadyExists
+func (s *Store) SaveMessage(ctx context.Context, notif models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.taken(notif.UUID) {
+		return models.ErrAlreadyExists
+	}
+	s.create(notif)
+	return nil
+}
+
+// SaveMessages сохраняет пачку новых уведомлений, каждое независимо от остальных;
+// возвращает ошибку для каждого уведомления, как redisdb.RedisConnection.SaveMessages
+func (s *Store) SaveMessages(ctx context.Context, notifs []models.Notification) ([]error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]error, len(notifs))
+	for i, notif := range notifs {
+		if s.taken(notif.UUID) {
+			results[i] = models.ErrAlreadyExists
+			continue
+		}
+		s.create(notif)
+	}
+	return results, nil
+}
+
+// taken проверяет, занят ли UUID уведомлением или рассылкой
+func (s *Store) taken(uuid string) bool {
+	_, message := s.records[uuid]
+	_, fanout := s.fanouts[uuid]
+	return message || fanout
+}
+
+func (s *Store) create(notif models.Notification) {
+	r := &record{notification: clone(notif)}
+	change := models.StatusChange{Status: notif.Status, At: time.Now()}
+	r.history = []models.StatusChange{change}
+	s.records[notif.UUID] = r
+	s.outbox[notif.UUID] = time.Now()
+	s.publishEvent(r, change)
+}
+
+// SaveFanout сохраняет рассылку и уведомления её получателям, которые сразу ставятся в outbox;
+// если UUID рассылки или получателя занят, возвращается models.ErrAlreadyExists
+func (s *Store) SaveFanout(ctx context.Context, parent models.Notification, children []models.Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.taken(parent.UUID) {
+		return models.ErrAlreadyExists
+	}
+	for _, child := range children {
+		if s.taken(child.UUID) {
+			return models.ErrAlreadyExists
+		}
+	}
+
+	s.fanouts[parent.UUID] = clone(parent)
+	for _, child := range children {
+		s.create(child)
+	}
+	return nil
+}
+
+// GetFanout загружает рассылку по UUID; если её нет, возвращает models.ErrNotFound
+func (s *Store) GetFanout(ctx context.Context, uuid string) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent, ok := s.fanouts[uuid]
+	if !ok {
+		return models.Notification{}, models.ErrNotFound
+	}
+	return clone(parent), nil
+}
+
+// DeleteMessage удаляет уведомление (или запись рассылки) вместе с историей попыток и статусов
+func (s *Store) DeleteMessage(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, uuid)
+	delete(s.outbox, uuid)
+	delete(s.fanouts, uuid)
+	return nil
+}
+
+// GetMessage загружает уведомление по UUID; если записи нет, возвращает models.ErrNotFound
+func (s *Store) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return models.Notification{}, models.ErrNotFound
+	}
+	return clone(r.notification), nil
+}
+
+// GetHistory возвращает смены статуса уведомления в порядке их записи
+func (s *Store) GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return []models.StatusChange{}, nil
+	}
+	return slices.Clone(r.history), nil
+}
+
+func (s *Store) SaveStatus(ctx context.Context, uuid string, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[uuid]; ok {
+		s.setStatus(r, status)
+	}
+	return nil
+}
+
+// SaveAttempt сохраняет неудачную попытку доставки в историю и как последнюю ошибку
+func (s *Store) SaveAttempt(ctx context.Context, uuid string, attempt models.Attempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[uuid]; ok {
+		r.notification.LastError = attempt.Error
+		r.attempts = append(r.attempts, attempt)
+	}
+	return nil
+}
+
+// GetAttempts возвращает историю неудачных попыток доставки
+func (s *Store) GetAttempts(ctx context.Context, uuid string) ([]models.Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return []models.Attempt{}, nil
+	}
+	return slices.Clone(r.attempts), nil
+}
+
+// MarkDead добавляет уведомление в индекс dead-letter
+func (s *Store) MarkDead(ctx context.Context, uuid string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dead[uuid] = at
+	return nil
+}
+
+// ListDead возвращает до limit последних dead-letter уведомлений (limit <= 0 – все)
+func (s *Store) ListDead(ctx context.Context, limit int) ([]models.DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dead := make([]models.DeadLetter, 0, len(s.dead))
+	for uuid, at := range s.dead {
+		r, ok := s.records[uuid]
+		if !ok {
+			continue
+		}
+		dead = append(dead, models.DeadLetter{
+			Notification: clone(r.notification),
+			FailedAt:     at,
+			History:      slices.Clone(r.attempts),
+		})
+	}
+	slices.SortFunc(dead, func(a, b models.DeadLetter) int {
+		return b.FailedAt.Compare(a.FailedAt)
+	})
+	if limit > 0 && len(dead) > limit {
+		dead = dead[:limit]
+	}
+	return dead, nil
+}
+
+// ReviveDead готовит dead-letter уведомление к повторной отправке и в той же операции ставит
+// его в outbox; если его нет в индексе, возвращает models.ErrNotFound
+func (s *Store) ReviveDead(ctx context.Context, uuid string) (models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if _, dead := s.dead[uuid]; !dead || !ok {
+		return models.Notification{}, models.ErrNotFound
+	}
+	delete(s.dead, uuid)
+	r.notification.Attempts = 0
+	s.setStatus(r, models.StatusPending)
+	s.requeue(r, time.Now())
+	return clone(r.notification), nil
+}
+
+// IncrAttempts увеличивает счётчик попыток доставки и возвращает новое значение
+func (s *Store) IncrAttempts(ctx context.Context, uuid string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return 0, models.ErrNotFound
+	}
+	r.notification.Attempts++
+	return r.notification.Attempts, nil
+}
+
+// TransitionStatus переводит уведомление в статус to, только если текущий статус один из from.
+// Возвращает предыдущий статус; models.ErrNotFound – если записи нет,
+// models.ErrStatusConflict – если текущий статус не входит в from.
+func (s *Store) TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return "", models.ErrNotFound
+	}
+	status := r.notification.Status
+	if !slices.Contains(from, status) {
+		return status, fmt.Errorf("%w: status is %q", models.ErrStatusConflict, status)
+	}
+	s.setStatus(r, to)
+	return status, nil
+}
+
+// CancelMessage отменяет ожидающее уведомление или приостановленную серию
+func (s *Store) CancelMessage(ctx context.Context, uuid string) error {
+	_, err := s.TransitionStatus(ctx, uuid, models.StatusCancelled, models.StatusPending, models.StatusRetrying, models.StatusPaused)
+	return err
+}
+
+// Reschedule сохраняет статус и новое время срабатывания уведомления
+func (s *Store) Reschedule(ctx context.Context, uuid string, status string, fireAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[uuid]; ok {
+		r.notification.FireAt = fireAt
+		s.setStatus(r, status)
+	}
+	return nil
+}
+
+// Postpone откладывает доставку уведомления до fireAt: сохраняет статус и время срабатывания,
+// в историю записывается причина отсрочки
+func (s *Store) Postpone(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[uuid]; ok {
+		r.notification.FireAt = fireAt
+		change := models.StatusChange{Status: status, At: time.Now(), Reason: reason, Until: fireAt}
+		r.setStatus(change)
+		s.publishEvent(r, change)
+	}
+	return nil
+}
+
+// EditMessage сохраняет текст и расписание ожидающего уведомления и меняет версию с version на newVersion;
+// перенесённое (newVersion != version) уведомление в той же операции ставится в outbox.
+// models.ErrStatusConflict – уведомление не ожидает отправки или его уже изменили (версия другая).
+func (s *Store) EditMessage(ctx context.Context, notif models.Notification, version int, newVersion int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[notif.UUID]
+	if !ok {
+		return models.ErrNotFound
+	}
+	if r.notification.Status != models.StatusPending {
+		return fmt.Errorf("%w: status is %q", models.ErrStatusConflict, r.notification.Status)
+	}
+	if r.notification.Version != version {
+		return fmt.Errorf("%w: notification is modified concurrently (version %d)", models.ErrStatusConflict, r.notification.Version)
+	}
+
+	r.notification.Version = newVersion
+	r.notification.Message = notif.Message
+	r.notification.Subject = notif.Subject
+	r.notification.ScheduledAt = notif.ScheduledAt
+	r.notification.FireAt = notif.FireAt
+	r.notification.Timezone = notif.Timezone
+	if newVersion != version {
+		s.requeue(r, time.Now())
+	}
+	return nil
+}
+
+// ResumeSeries возобновляет приостановленную серию с версией расписания version: переводит её
+// в ожидание со следующим срабатыванием fireAt и новой версией и в той же операции ставит в outbox.
+// models.ErrStatusConflict – серия не приостановлена или её расписание уже изменили.
+func (s *Store) ResumeSeries(ctx context.Context, uuid string, version int, fireAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return models.ErrNotFound
+	}
+	if r.notification.Status != models.StatusPaused {
+		return fmt.Errorf("%w: status is %q", models.ErrStatusConflict, r.notification.Status)
+	}
+	if r.notification.Version != version {
+		return fmt.Errorf("%w: series is modified concurrently (version %d)", models.ErrStatusConflict, r.notification.Version)
+	}
+
+	r.notification.Version++
+	r.notification.FireAt = fireAt
+	s.setStatus(r, models.StatusPending)
+	s.requeue(r, time.Now())
+	return nil
+}
+
+// CompleteOccurrence засчитывает срабатывание серии и обнуляет счётчик попыток для следующего
+func (s *Store) CompleteOccurrence(ctx context.Context, uuid string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return 0, models.ErrNotFound
+	}
+	r.notification.Occurrences++
+	r.notification.Attempts = 0
+	return r.notification.Occurrences, nil
+}
+
+// Backlog считает наступившие к now, но ещё не доставленные уведомления (pending и retrying) по приоритету
+func (s *Store) Backlog(ctx context.Context, now time.Time) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backlog := make(map[string]int, len(models.Priorities))
+	for _, priority := range models.Priorities {
+		backlog[priority] = 0
+	}
+	for _, r := range s.records {
+		n := r.notification
+		if (n.Status == models.StatusPending || n.Status == models.StatusRetrying) && !n.FireAt.After(now) {
+			backlog[models.Priorities[models.PriorityLevel(n.Priority)]]++
+		}
+	}
+	return backlog, nil
+}
+
+// ListMessages возвращает уведомления по фильтру в порядке redisdb: по fire_at, затем по UUID
+func (s *Store) ListMessages(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error) {
+	var after *position
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &cursor
+	}
+	// пустая страница: limit задаёт вызывающий, хранилище не должно паниковать на нуле
+	if filter.Limit <= 0 {
+		return []models.Notification{}, "", nil
+	}
+
+	s.mu.Lock()
+	matched := make([]models.Notification, 0, len(s.records))
+	for _, r := range s.records {
+		n := r.notification
+		score := unixMilli(n.FireAt)
+		switch {
+		case filter.Status != "" && n.Status != filter.Status,
+			filter.Recipient != "" && n.Recipient != filter.Recipient,
+			filter.Tag != "" && !slices.Contains(n.Tags, filter.Tag),
+			!filter.From.IsZero() && score < filter.From.UnixMilli(),
+			!filter.To.IsZero() && score > filter.To.UnixMilli(),
+			after != nil && !positionOf(n).after(*after):
+			continue
+		}
+		matched = append(matched, clone(n))
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(matched, func(a, b models.Notification) int {
+		if positionOf(a).after(positionOf(b)) {
+			return 1
+		}
+		return -1
+	})
+	if len(matched) > filter.Limit {
+		return matched[:filter.Limit], positionOf(matched[filter.Limit-1]).encode(), nil
+	}
+	return matched, "", nil
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности на ttl. Если ключ уже занят,
+// возвращает сохранённую запись и false.
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key string, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.idempotency[key]; ok && time.Now().Before(entry.expires) {
+		return entry.record, false, nil
+	}
+	record := models.IdempotencyRecord{Fingerprint: fingerprint}
+	s.idempotency[key] = idempotencyEntry{record: record, expires: time.Now().Add(ttl)}
+	return record, true, nil
+}
+
+// SaveIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (s *Store) SaveIdempotencyKey(ctx context.Context, key string, record models.IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idempotency[key] = idempotencyEntry{record: record, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ после неудачного запроса
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.idempotency, key)
+	return nil
+}
+
+// DueOutbox возвращает до limit записей outbox, время публикации которых наступило к now
+func (s *Store) DueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]models.OutboxEntry, 0, len(s.outbox))
+	for uuid, dueAt := range s.outbox {
+		if !dueAt.After(now) {
+			entries = append(entries, models.OutboxEntry{UUID: uuid, DueAt: dueAt})
+		}
+	}
+	slices.SortFunc(entries, func(a, b models.OutboxEntry) int {
+		return a.DueAt.Compare(b.DueAt)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// AckOutbox удаляет опубликованную запись outbox, если её не поставили заново
+func (s *Store) AckOutbox(ctx context.Context, entry models.OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dueAt, ok := s.outbox[entry.UUID]; ok && dueAt.Equal(entry.DueAt) {
+		delete(s.outbox, entry.UUID)
+	}
+	return nil
+}
+
+// DelayOutbox откладывает повторную публикацию записи outbox до until
+func (s *Store) DelayOutbox(ctx context.Context, entry models.OutboxEntry, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dueAt, ok := s.outbox[entry.UUID]; ok && dueAt.Equal(entry.DueAt) {
+		s.outbox[entry.UUID] = until
+	}
+	return nil
+}
+
+// EnqueueOutbox ставит уведомление в outbox, если его там ещё нет, и запоминает время постановки
+func (s *Store) EnqueueOutbox(ctx context.Context, uuid string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[uuid]
+	if !ok {
+		return nil
+	}
+	r.enqueuedAt = at
+	if _, ok := s.outbox[uuid]; !ok {
+		s.outbox[uuid] = at
+	}
+	return nil
+}
+
+// requeue ставит запись в outbox на at, как requeue в скриптах redisdb: время перезаписывается,
+// чтобы relay, публикующий прежнюю версию, не снял новую запись; вызывается под s.mu
+func (s *Store) requeue(r *record, at time.Time) {
+	r.enqueuedAt = at
+	s.outbox[r.notification.UUID] = at
+}
+
+// StaleMessages возвращает до limit самых старых уведомлений в статусе status, чьё время
+// срабатывания и последняя постановка в outbox раньше before; порядок – как в индексе redisdb
+func (s *Store) StaleMessages(ctx context.Context, status string, before time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stale []position
+	for _, r := range s.records {
+		if r.notification.Status == status && unixMilli(r.notification.FireAt) < before.UnixMilli() &&
+			unixMilli(r.enqueuedAt) < before.UnixMilli() {
+			stale = append(stale, positionOf(r.notification))
+		}
+	}
+	slices.SortFunc(stale, func(a, b position) int {
+		if a.after(b) {
+			return 1
+		}
+		return -1
+	})
+
+	uuids := make([]string, 0, min(limit, len(stale)))
+	for _, p := range stale[:min(limit, len(stale))] {
+		uuids = append(uuids, p.uuid)
+	}
+	return uuids, nil
+}
+
+// SaveTemplate сохраняет новую версию шаблона и возвращает его с номером версии
+func (s *Store) SaveTemplate(ctx context.Context, template models.Template) (models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	template.Variables = slices.Clone(template.Variables)
+	template.Version = len(s.templates[template.Name]) + 1
+	s.templates[template.Name] = append(s.templates[template.Name], template)
+	return template, nil
+}
+
+// GetTemplate загружает версию шаблона (0 – последнюю); models.ErrNotFound – если её нет
+func (s *Store) GetTemplate(ctx context.Context, name string, version int) (models.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.templates[name]
+	if version == 0 {
+		version = len(versions)
+	}
+	if version < 1 || version > len(versions) {
+		return models.Template{}, models.ErrNotFound
+	}
+	return versions[version-1], nil
+}
+
+// TakeTokens атомарно забирает по токену из всех корзин или возвращает время ожидания токенов
+func (s *Store) TakeTokens(ctx context.Context, buckets []ratelimit.Bucket, now time.Time) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var wait time.Duration
+	tokens := make([]float64, len(buckets))
+	for i, bucket := range buckets {
+		state, ok := s.buckets[bucket.Key]
+		if !ok {
+			state = bucketState{tokens: float64(bucket.Burst), at: now}
+		}
+		tokens[i] = bucket.Available(state.tokens, state.at, now)
+		wait = max(wait, bucket.Wait(tokens[i]))
+	}
+	if wait > 0 {
+		return wait, nil
+	}
+
+	for i, bucket := range buckets {
+		s.buckets[bucket.Key] = bucketState{tokens: tokens[i] - 1, at: now}
+	}
+	return 0, nil
+}
+
+// время хранится в миллисекундах Unix, 0 – не задано
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// position позиция уведомления в выдаче ListMessages; курсор совместим с redisdb
+type position struct {
+	score int64
+	uuid  string
+}
+
+func positionOf(n models.Notification) position {
+	return position{score: unixMilli(n.FireAt), uuid: n.UUID}
+}
+
+func (p position) after(other position) bool {
+	if p.score != other.score {
+		return p.score > other.score
+	}
+	return p.uuid > other.uuid
+}
+
+func (p position) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(p.score, 10) + ":" + p.uuid))
+}
+
+func decodeCursor(value string) (position, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return position{}, models.ErrInvalidCursor
+	}
+	score, uuid, ok := strings.Cut(string(data), ":")
+	if !ok || uuid == "" {
+		return position{}, models.ErrInvalidCursor
+	}
+	parsed, err := strconv.ParseInt(score, 10, 64)
+	if err != nil {
+		return position{}, models.ErrInvalidCursor
+	}
+	return position{score: parsed, uuid: uuid}, nil
+}
+
+// SavePreferences сохраняет настройки получателя, заменяя прежние
+func (s *Store) SavePreferences(ctx context.Context, prefs models.Preferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs.QuietHours = slices.Clone(prefs.QuietHours)
+	s.preferences[prefs.Channel+":"+prefs.Address] = prefs
+	return nil
+}
+
+// GetPreferences загружает настройки получателя; models.ErrNotFound – если их нет
+func (s *Store) GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefs, ok := s.preferences[channel+":"+address]
+	if !ok {
+		return models.Preferences{}, models.ErrNotFound
+	}
+	prefs.QuietHours = slices.Clone(prefs.QuietHours)
+	return prefs, nil
+}
+
+// DeletePreferences удаляет настройки получателя; models.ErrNotFound – если их нет
+func (s *Store) DeletePreferences(ctx context.Context, channel string, address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := channel + ":" + address
+	if _, ok := s.preferences[key]; !ok {
+		return models.ErrNotFound
+	}
+	delete(s.preferences, key)
+	return nil
+}
+
+// DueCallbacks возвращает до limit событий, время отправки которых наступило к now, по времени отправки
+func (s *Store) DueCallbacks(ctx context.Context, now time.Time, limit int) ([]models.CallbackEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []models.CallbackEntry
+	for key, at := range s.callbacks {
+		if at.After(now) {
+			continue
+		}
+		var event models.CallbackEvent
+		if err := json.Unmarshal([]byte(key), &event); err != nil {
+			delete(s.callbacks, key)
+			continue
+		}
+		entries = append(entries, models.CallbackEntry{Event: event, Key: key, DueAt: at})
+	}
+	slices.SortFunc(entries, func(a, b models.CallbackEntry) int {
+		return a.DueAt.Compare(b.DueAt)
+	})
+	return entries[:min(limit, len(entries))], nil
+}
+
+// AckCallback удаляет отправленное (или отброшенное) событие из очереди
+func (s *Store) AckCallback(ctx context.Context, entry models.CallbackEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.callbacks, entry.Key)
+	return nil
+}
+
+// RetryCallback откладывает повторную отправку события до until, увеличивая его счётчик попыток
+func (s *Store) RetryCallback(ctx context.Context, entry models.CallbackEntry, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.callbacks[entry.Key]; !ok {
+		return nil
+	}
+	delete(s.callbacks, entry.Key)
+	event := entry.Event
+	event.Attempts++
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.callbacks[string(data)] = until
+	return nil
+}
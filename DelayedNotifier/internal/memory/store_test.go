@@ -0,0 +1,333 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/ratelimit"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStore_Transitions(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+
+	if err := s.SaveMessage(ctx, models.Notification{UUID: "n-1", Status: models.StatusPending}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.SaveMessage(ctx, models.Notification{UUID: "n-1", Status: models.StatusPending}); !errors.Is(err, models.ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists, got %v", err)
+	}
+
+	if _, err := s.TransitionStatus(ctx, "n-1", models.StatusProcessing, models.StatusPending); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.CancelMessage(ctx, "n-1"); !errors.Is(err, models.ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict, got %v", err)
+	}
+	if err := s.CancelMessage(ctx, "unknown"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	history, _ := s.GetHistory(ctx, "n-1")
+	if len(history) != 2 || history[1].Status != models.StatusProcessing {
+		t.Errorf("Unexpected history: %v", history)
+	}
+
+	// новое уведомление сразу попадает в outbox
+	entries, _ := s.DueOutbox(ctx, time.Now(), 10)
+	if len(entries) != 1 || entries[0].UUID != "n-1" {
+		t.Fatalf("Expected n-1 in outbox, got %v", entries)
+	}
+	s.EnqueueOutbox(ctx, "n-1", time.Now().Add(time.Hour))
+	s.AckOutbox(ctx, entries[0])
+	if entries, _ := s.DueOutbox(ctx, time.Now(), 10); len(entries) != 0 {
+		t.Errorf("Expected acked entry to be removed, got %v", entries)
+	}
+}
+
+func TestStore_EditMessage(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.SaveMessage(ctx, models.Notification{UUID: "n-1", Status: models.StatusPending})
+	entries, _ := s.DueOutbox(ctx, time.Now(), 10)
+	s.AckOutbox(ctx, entries[0])
+
+	// правка текста не меняет версию и не публикует уведомление заново
+	edit := models.Notification{UUID: "n-1"}
+	edit.Message = "Fixed text"
+	if err := s.EditMessage(ctx, edit, 0, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, _ := s.DueOutbox(ctx, time.Now(), 10); len(entries) != 0 {
+		t.Errorf("Expected no outbox entry for a text edit, got %v", entries)
+	}
+
+	if err := s.EditMessage(ctx, edit, 0, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if entries, _ := s.DueOutbox(ctx, time.Now(), 10); len(entries) != 1 || entries[0].UUID != "n-1" {
+		t.Errorf("Expected rescheduled n-1 in outbox, got %v", entries)
+	}
+	if err := s.EditMessage(ctx, edit, 0, 1); !errors.Is(err, models.ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict for a stale version, got %v", err)
+	}
+}
+
+func TestStore_ReviveDead(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.SaveMessage(ctx, models.Notification{UUID: "n-1", Status: models.StatusFailed})
+	entries, _ := s.DueOutbox(ctx, time.Now(), 10)
+	s.AckOutbox(ctx, entries[0])
+	s.MarkDead(ctx, "n-1", time.Now())
+
+	if _, err := s.ReviveDead(ctx, "n-1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// повторную отправку публикует outbox relay
+	if entries, _ := s.DueOutbox(ctx, time.Now(), 10); len(entries) != 1 || entries[0].UUID != "n-1" {
+		t.Errorf("Expected revived n-1 in outbox, got %v", entries)
+	}
+	if _, err := s.ReviveDead(ctx, "n-1"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a revived notification, got %v", err)
+	}
+}
+
+func TestStore_ResumeSeries(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.SaveMessage(ctx, models.Notification{UUID: "series", Status: models.StatusPaused, Version: 2})
+	entries, _ := s.DueOutbox(ctx, time.Now(), 10)
+	s.AckOutbox(ctx, entries[0])
+
+	fireAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	if err := s.ResumeSeries(ctx, "series", 1, fireAt); !errors.Is(err, models.ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict for a stale version, got %v", err)
+	}
+	if err := s.ResumeSeries(ctx, "series", 2, fireAt); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resumed, _ := s.GetMessage(ctx, "series")
+	if resumed.Status != models.StatusPending || resumed.Version != 3 || !resumed.FireAt.Equal(fireAt) {
+		t.Errorf("Unexpected resumed series: %+v", resumed)
+	}
+	if entries, _ := s.DueOutbox(ctx, time.Now(), 10); len(entries) != 1 || entries[0].UUID != "series" {
+		t.Errorf("Expected resumed series in outbox, got %v", entries)
+	}
+	if err := s.ResumeSeries(ctx, "series", 3, fireAt); !errors.Is(err, models.ErrStatusConflict) {
+		t.Errorf("Expected ErrStatusConflict for a pending series, got %v", err)
+	}
+}
+
+func TestStore_ListMessages(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	base := time.Now().Truncate(time.Millisecond)
+	for i, uuid := range []string{"c", "a", "b", "d"} {
+		s.SaveMessage(ctx, models.Notification{
+			UUID:   uuid,
+			Status: models.StatusPending,
+			FireAt: base.Add(time.Duration(i%2) * time.Minute),
+			NotificationCard: models.NotificationCard{
+				Tags: []string{"tag-" + uuid},
+			},
+		})
+	}
+
+	var listed []string
+	cursor := ""
+	for {
+		items, next, err := s.ListMessages(ctx, models.ListFilter{Limit: 3, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, n := range items {
+			listed = append(listed, n.UUID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	// по fire_at, при равенстве – по UUID
+	if got := fmt.Sprint(listed); got != "[b c a d]" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+
+	items, _, _ := s.ListMessages(ctx, models.ListFilter{Tag: "tag-a", Limit: 10})
+	if len(items) != 1 || items[0].UUID != "a" {
+		t.Errorf("Unexpected tag filter result: %v", items)
+	}
+	if _, _, err := s.ListMessages(ctx, models.ListFilter{Limit: 10, Cursor: "???"}); !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+	if items, next, err := s.ListMessages(ctx, models.ListFilter{}); err != nil || len(items) != 0 || next != "" {
+		t.Errorf("Expected empty page for zero limit, got %v %q %v", items, next, err)
+	}
+}
+
+func TestStore_SaveFanout(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	s.SaveMessage(ctx, models.Notification{UUID: "taken", Status: models.StatusPending})
+
+	parent := models.Notification{UUID: "parent", Status: models.StatusPending}
+	parent.Recipients = []models.Recipient{{UUID: "child-1"}, {UUID: "child-2"}}
+	children := []models.Notification{
+		{UUID: "child-1", ParentUUID: "parent", Status: models.StatusPending},
+		{UUID: "taken", ParentUUID: "parent", Status: models.StatusPending},
+	}
+	// занятый UUID получателя отменяет всю рассылку
+	if err := s.SaveFanout(ctx, parent, children); !errors.Is(err, models.ErrAlreadyExists) {
+		t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+	}
+	if _, err := s.GetMessage(ctx, "child-1"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected no partial fan-out, got %v", err)
+	}
+
+	children[1].UUID = "child-2"
+	if err := s.SaveFanout(ctx, parent, children); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.SaveMessage(ctx, models.Notification{UUID: "parent", Status: models.StatusPending}); !errors.Is(err, models.ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists for fan-out UUID, got %v", err)
+	}
+
+	saved, err := s.GetFanout(ctx, "parent")
+	if err != nil || len(saved.Recipients) != 2 {
+		t.Fatalf("Unexpected fan-out %v: %v", saved, err)
+	}
+	// рассылка не публикуется, в outbox только доставки получателям
+	entries, _ := s.DueOutbox(ctx, time.Now(), 10)
+	if len(entries) != 3 {
+		t.Errorf("Expected taken, child-1 and child-2 in outbox, got %v", entries)
+	}
+	for _, entry := range entries {
+		if entry.UUID == "parent" {
+			t.Errorf("Fan-out must not be published: %v", entries)
+		}
+	}
+}
+
+func TestStore_TakeTokens(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	now := time.Now()
+	channel := ratelimit.Bucket{Key: "channel:email", Rate: 1, Burst: 2}
+	recipient := ratelimit.Bucket{Key: "recipient:email:a", Rate: 1, Burst: 1}
+
+	if wait, _ := s.TakeTokens(ctx, []ratelimit.Bucket{channel, recipient}, now); wait != 0 {
+		t.Fatalf("Expected token, got wait %s", wait)
+	}
+	// получатель исчерпал лимит: токен канала не забирается
+	if wait, _ := s.TakeTokens(ctx, []ratelimit.Bucket{channel, recipient}, now); wait != time.Second {
+		t.Fatalf("Expected wait 1s, got %s", wait)
+	}
+	if wait, _ := s.TakeTokens(ctx, []ratelimit.Bucket{channel}, now); wait != 0 {
+		t.Errorf("Expected channel token to be left, got wait %s", wait)
+	}
+	if wait, _ := s.TakeTokens(ctx, []ratelimit.Bucket{channel, recipient}, now.Add(time.Second)); wait != 0 {
+		t.Errorf("Expected refilled tokens, got wait %s", wait)
+	}
+}
+
+func TestStore_Callbacks(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+
+	n := models.Notification{UUID: "n-1", Status: models.StatusPending}
+	n.CallbackURL = "https://example.com/hooks"
+	s.SaveMessage(ctx, n)
+	s.SaveMessage(ctx, models.Notification{UUID: "n-2", Status: models.StatusPending})
+
+	// о захвате worker-ом callback не отправляется, об отправке и отмене – да
+	s.TransitionStatus(ctx, "n-1", models.StatusProcessing, models.StatusPending)
+	s.SaveStatus(ctx, "n-1", models.StatusSent)
+	s.CancelMessage(ctx, "n-2")
+
+	entries, _ := s.DueCallbacks(ctx, time.Now(), 10)
+	if len(entries) != 1 || entries[0].Event.UUID != "n-1" || entries[0].Event.Status != models.StatusSent {
+		t.Fatalf("Expected sent event of n-1, got %v", entries)
+	}
+
+	s.RetryCallback(ctx, entries[0], time.Now().Add(time.Hour))
+	if due, _ := s.DueCallbacks(ctx, time.Now(), 10); len(due) != 0 {
+		t.Fatalf("Expected retried event to be delayed, got %v", due)
+	}
+	delayed, _ := s.DueCallbacks(ctx, time.Now().Add(2*time.Hour), 10)
+	if len(delayed) != 1 || delayed[0].Event.Attempts != 1 || delayed[0].Event.ID != entries[0].Event.ID {
+		t.Fatalf("Expected event with 1 attempt, got %v", delayed)
+	}
+
+	s.AckCallback(ctx, delayed[0])
+	if due, _ := s.DueCallbacks(ctx, time.Now().Add(2*time.Hour), 10); len(due) != 0 {
+		t.Errorf("Expected acked event to be removed, got %v", due)
+	}
+}
+
+func TestStore_Events(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewStore()
+	live, _ := s.SubscribeEvents(ctx)
+
+	n := models.Notification{UUID: "n-1", ParentUUID: "parent", Status: models.StatusPending}
+	n.Tags = []string{"promo"}
+	s.SaveMessage(ctx, n)
+	s.TransitionStatus(ctx, "n-1", models.StatusProcessing, models.StatusPending)
+	s.Postpone(ctx, "n-1", models.StatusPending, time.Now().Add(time.Hour), models.ReasonQuietHours)
+
+	var received []models.StatusEvent
+	for range 3 {
+		received = append(received, <-live)
+	}
+	if received[0].Status != models.StatusPending || received[0].ParentUUID != "parent" || received[0].Tags[0] != "promo" {
+		t.Errorf("Unexpected first event: %+v", received[0])
+	}
+	if received[2].Reason != models.ReasonQuietHours {
+		t.Errorf("Expected postpone reason in event, got %+v", received[2])
+	}
+
+	after, err := s.EventsAfter(ctx, received[0].ID, 10)
+	if err != nil || len(after) != 2 || after[0].ID != received[1].ID || after[1].ID != received[2].ID {
+		t.Fatalf("Expected 2 events after %s, got %v: %v", received[0].ID, after, err)
+	}
+	if after, _ := s.EventsAfter(ctx, received[0].ID, 1); len(after) != 1 {
+		t.Errorf("Expected limit to be applied, got %v", after)
+	}
+
+	cancel()
+	if _, ok := <-live; ok {
+		t.Error("Expected subscription to be closed with ctx")
+	}
+}
+
+func TestStore_StaleMessages(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore()
+	now := time.Now()
+	for _, uuid := range []string{"a", "b"} {
+		s.SaveMessage(ctx, models.Notification{UUID: uuid, Status: models.StatusPending, FireAt: now.Add(-time.Hour)})
+	}
+
+	// недавно возвращённое в outbox уведомление ещё не считается потерянным
+	s.EnqueueOutbox(ctx, "a", now)
+	stale, _ := s.StaleMessages(ctx, models.StatusPending, now.Add(-time.Minute), 10)
+	if fmt.Sprint(stale) != "[b]" {
+		t.Errorf("Expected only b to be stale, got %v", stale)
+	}
+
+	// как и в индексе redisdb, выдаются самые старые по fire_at
+	for i, uuid := range []string{"e", "d", "c"} {
+		s.SaveMessage(ctx, models.Notification{UUID: uuid, Status: models.StatusRetrying, FireAt: now.Add(-time.Duration(i+1) * time.Hour)})
+	}
+	for range 10 {
+		stale, _ := s.StaleMessages(ctx, models.StatusRetrying, now, 2)
+		if fmt.Sprint(stale) != "[c d]" {
+			t.Fatalf("Expected the oldest [c d], got %v", stale)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"DelayedNotifier/internal/models"
+	"sync"
+	"time"
+)
+
+// wheelSize число слотов колеса; задержка длиннее wheelSize тиков проходит колесо несколько кругов
+const wheelSize = 512
+
+type timer struct {
+	message message
+	rounds  int // сколько полных оборотов колеса осталось до срабатывания
+}
+
+// timingWheel хешированное колесо таймеров: сообщение с задержкой в n тиков кладётся в слот
+// на n позиций впереди курсора, и advance выдаёт его, когда курсор дойдёт до слота на нужном обороте.
+// Добавление и срабатывание – O(1) независимо от числа отложенных сообщений.
+type timingWheel struct {
+	mu     sync.Mutex
+	tick   time.Duration
+	slots  [wheelSize][]timer
+	cursor int
+	size   int
+}
+
+func newTimingWheel(tick time.Duration) *timingWheel {
+	return &timingWheel{tick: tick}
+}
+
+// add откладывает сообщение на delay; задержка округляется вверх до целого числа тиков
+// и ограничивается models.MaxScheduleDelay, чтобы округление не переполнилось.
+// Возвращает false, если задержки нет и сообщение нужно доставить сразу.
+func (w *timingWheel) add(m message, delay time.Duration) bool {
+	delay = min(delay, models.MaxScheduleDelay)
+	ticks := int((delay + w.tick - 1) / w.tick)
+	if ticks <= 0 {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// слот посещается через ticks, ticks+wheelSize, ... продвижений курсора
+	slot := (w.cursor + ticks) % wheelSize
+	rounds := (ticks - 1) / wheelSize
+	w.slots[slot] = append(w.slots[slot], timer{message: m, rounds: rounds})
+	w.size++
+	return true
+}
+
+// advance сдвигает курсор на один тик и возвращает наступившие сообщения
+func (w *timingWheel) advance() []message {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.cursor = (w.cursor + 1) % wheelSize
+	slot := w.slots[w.cursor]
+	if len(slot) == 0 {
+		return nil
+	}
+
+	var due []message
+	pending := slot[:0]
+	for _, t := range slot {
+		if t.rounds == 0 {
+			due = append(due, t.message)
+			continue
+		}
+		t.rounds--
+		pending = append(pending, t)
+	}
+	w.slots[w.cursor] = pending
+	w.size -= len(due)
+	return due
+}
+
+// len число отложенных сообщений
+func (w *timingWheel) len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
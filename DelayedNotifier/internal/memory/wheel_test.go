@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTimingWheel(t *testing.T) {
+	tests := []struct {
+		name          string
+		delay         time.Duration
+		expectedTicks int // 0 – доставляется сразу
+	}{
+		{name: "No delay", delay: 0, expectedTicks: 0},
+		{name: "Partial tick rounds up", delay: 5 * time.Millisecond, expectedTicks: 1},
+		{name: "Several ticks", delay: 30 * time.Millisecond, expectedTicks: 3},
+		{name: "Exactly one round", delay: wheelSize * 10 * time.Millisecond, expectedTicks: wheelSize},
+		{name: "Several rounds", delay: (2*wheelSize + 7) * 10 * time.Millisecond, expectedTicks: 2*wheelSize + 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTimingWheel(10 * time.Millisecond)
+			// курсор не в начале колеса
+			for i := 0; i < 100; i++ {
+				w.advance()
+			}
+
+			if added := w.add(message{uuid: "n-1"}, tt.delay); added != (tt.expectedTicks > 0) {
+				t.Fatalf("Expected added %v, got %v", tt.expectedTicks > 0, added)
+			}
+			for tick := 1; tick <= tt.expectedTicks; tick++ {
+				due := w.advance()
+				if tick < tt.expectedTicks && len(due) != 0 {
+					t.Fatalf("Message fired early at tick %d", tick)
+				}
+				if tick == tt.expectedTicks && (len(due) != 1 || due[0].uuid != "n-1") {
+					t.Fatalf("Expected message at tick %d, got %v", tick, due)
+				}
+			}
+			if w.len() != 0 {
+				t.Errorf("Expected empty wheel, got %d", w.len())
+			}
+		})
+	}
+}
+
+func TestTimingWheel_MaxDelay(t *testing.T) {
+	w := newTimingWheel(time.Millisecond)
+	// округление до тиков не переполняется и на максимальной длительности
+	if !w.add(message{uuid: "n-1"}, time.Duration(math.MaxInt64)) {
+		t.Fatal("Expected message to be scheduled")
+	}
+	if len(w.advance()) != 0 || w.len() != 1 {
+		t.Errorf("Expected message to stay scheduled, got %d", w.len())
+	}
+}
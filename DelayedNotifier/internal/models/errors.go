@@ -0,0 +1,16 @@
+package models
+
+import "errors"
+
+var (
+	// ErrNotFound возвращается хранилищем, если уведомления с таким UUID нет
+	ErrNotFound = errors.New("notification not found")
+	// ErrStatusConflict возвращается, если текущий статус уведомления не допускает перехода
+	ErrStatusConflict = errors.New("notification status does not allow this transition")
+	// ErrAlreadyExists возвращается при создании уведомления с уже занятым UUID
+	ErrAlreadyExists = errors.New("notification already exists")
+	// ErrInvalidCursor возвращается для повреждённого курсора пагинации
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrDelayTooLong возвращается планировщиком для задержки больше MaxScheduleDelay
+	ErrDelayTooLong = errors.New("schedule delay exceeds the limit")
+)
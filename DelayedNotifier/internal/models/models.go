@@ -1,5 +1,10 @@
 package models
 
+import (
+	"slices"
+	"time"
+)
+
 const (
 	StatusPending    = "pending"    // Ожидает отправки
 	StatusProcessing = "processing" // В процессе отправки
@@ -7,15 +12,269 @@ const (
 	StatusFailed     = "failed"     // Не удалось отправить
 	StatusCancelled  = "cancelled"  // Отменено пользователем
 	StatusRetrying   = "retrying"   // Повторная попытка отправки
+	StatusPaused     = "paused"     // Серия повторяющихся уведомлений приостановлена
+)
+
+// Statuses все статусы уведомления
+var Statuses = []string{
+	StatusPending, StatusProcessing, StatusSent, StatusFailed, StatusCancelled, StatusRetrying, StatusPaused,
+}
+
+// StatusPartial сводный статус рассылки, в которой часть получателей получила уведомление,
+// а остальные доставки завершились неудачей или отменены; у отдельных уведомлений не бывает
+const StatusPartial = "partial"
+
+// AggregateStatus сводный статус рассылки по статусам доставок получателям:
+// пока есть незавершённые доставки, статус определяют они, после – итог всех доставок
+func AggregateStatus(statuses []string) string {
+	var active []string
+	sent := false
+	for _, status := range statuses {
+		switch status {
+		case StatusSent:
+			sent = true
+		case StatusFailed, StatusCancelled:
+		default:
+			active = append(active, status)
+		}
+	}
+
+	switch {
+	case len(active) > 0:
+		if same(active) {
+			return active[0]
+		}
+		return StatusProcessing
+	case same(statuses):
+		return statuses[0]
+	case sent:
+		return StatusPartial
+	default:
+		return StatusFailed
+	}
+}
+
+func same(statuses []string) bool {
+	for _, status := range statuses {
+		if status != statuses[0] {
+			return false
+		}
+	}
+	return len(statuses) > 0
+}
+
+// MaxDelay потолок задержки rabbitmq-delayed-message-exchange (2^32-1 мс, около 49 дней).
+// Ограничение действует только для планировщика rabbitmq, см. scheduler.Scheduler.MaxDelay.
+const MaxDelay = (1<<32 - 1) * time.Millisecond
+
+// MaxScheduleDelay потолок задержки для любого планировщика (10 лет): scheduled_at больше
+// около 9.2e12 мс переполнил бы time.Duration при переводе в наносекунды
+const MaxScheduleDelay = 10 * 365 * 24 * time.Hour
+
+// Каналы доставки уведомлений
+const (
+	ChannelLog      = "log"      // Запись в лог сервиса (канал по умолчанию)
+	ChannelEmail    = "email"    // Письмо по SMTP
+	ChannelTelegram = "telegram" // Сообщение через Telegram Bot API
+	ChannelWebhook  = "webhook"  // Подписанный POST запрос на URL получателя
 )
 
 type Notification struct {
-	UUID   string `json:"uuid"`
-	Status string `json:"status" redisdb:"status"` // e.g., "pending", "sent", "failed"
+	UUID      string `json:"uuid"`
+	Status    string `json:"status" redisdb:"status"` // e.g., "pending", "sent", "failed"
+	LastError string `json:"last_error,omitempty" redisdb:"last_error"`
+	Attempts  int    `json:"attempts" redisdb:"attempts"` // число выполненных попыток доставки
+	// FireAt абсолютное время отправки, вычисленное сервером из send_at или scheduled_at
+	FireAt time.Time `json:"fire_at,omitzero" redisdb:"fire_at"`
+	// Occurrences число завершённых срабатываний повторяющегося уведомления
+	Occurrences int `json:"occurrences,omitempty" redisdb:"occurrences"`
+	// Version версия расписания: растёт при переносе, сообщения из очереди со старой версией отбрасываются
+	Version int `json:"version" redisdb:"version"`
+	// Format формат текста после рендера шаблона (TemplateFormatHTML – разметка); не хранится
+	Format string `json:"-" redisdb:"-"`
+	// ParentUUID рассылка, доставкой одному из получателей которой является уведомление
+	ParentUUID string `json:"parent_uuid,omitempty" redisdb:"parent_uuid"`
 	NotificationCard
 }
 
 type NotificationCard struct {
 	Message     string `json:"message" redisdb:"message"`
-	ScheduledAt int64  `json:"scheduled_at" redisdb:"-"`
+	ScheduledAt int64  `json:"scheduled_at" redisdb:"scheduled_at"` // задержка в миллисекундах
+	// SendAt время отправки в RFC 3339 или без смещения вместе с IANA Timezone
+	SendAt      string `json:"send_at,omitempty" redisdb:"-"`
+	Timezone    string `json:"timezone,omitempty" redisdb:"timezone"`
+	AllowPast   bool   `json:"allow_past,omitempty" redisdb:"-"`        // разрешить send_at в прошлом (отправка сразу)
+	Channel     string `json:"channel,omitempty" redisdb:"channel"`     // e.g., "log", "email", "telegram", "webhook"
+	Recipient   string `json:"recipient,omitempty" redisdb:"recipient"` // адрес получателя в терминах канала
+	Subject     string `json:"subject,omitempty" redisdb:"subject"`
+	MaxAttempts int    `json:"max_attempts,omitempty" redisdb:"max_attempts"` // 0 – значение из конфигурации
+	// Cron делает уведомление повторяющимся: после каждого срабатывания планируется следующее
+	Cron           string    `json:"cron,omitempty" redisdb:"cron"`
+	Until          time.Time `json:"until,omitzero" redisdb:"until"`                      // последнее допустимое срабатывание
+	MaxOccurrences int       `json:"max_occurrences,omitempty" redisdb:"max_occurrences"` // 0 – без ограничения
+	Tags           []string  `json:"tags,omitempty" redisdb:"tags"`                       // метки для фильтрации списка
+	// Priority порядок доставки наступивших уведомлений: более приоритетные worker забирает первыми;
+	// PriorityUrgent – срочное уведомление, доставляется и в тихие часы получателя
+	Priority string `json:"priority,omitempty" redisdb:"priority"`
+	// CallbackURL адрес, на который сервис отправляет подписанные события смены статуса (CallbackStatuses)
+	CallbackURL string `json:"callback_url,omitempty" redisdb:"callback_url"`
+	// Tenant клиент сервиса: для него могут быть переопределены лимиты доставки (config.RateLimit)
+	Tenant string `json:"tenant,omitempty" redisdb:"tenant"`
+	// Template имя шаблона: текст и тема рендерятся из него с Data при доставке, Message не задаётся
+	Template        string         `json:"template,omitempty" redisdb:"template"`
+	TemplateVersion int            `json:"template_version,omitempty" redisdb:"template_version"` // 0 при создании – последняя версия
+	Data            map[string]any `json:"data,omitempty" redisdb:"data"`                         // переменные шаблона
+	// Recipients делает уведомление рассылкой: каждому получателю создаётся отдельное уведомление
+	// со своим статусом, вместо Channel и Recipient
+	Recipients []Recipient `json:"recipients,omitempty" redisdb:"-"`
+}
+
+// Приоритеты уведомлений; пустой приоритет – PriorityNormal
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Priorities все приоритеты уведомления по возрастанию
+var Priorities = []string{PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent}
+
+// PriorityLevel номер приоритета в Priorities: 0 – PriorityLow; пустой и неизвестный – PriorityNormal
+func PriorityLevel(priority string) int {
+	if level := slices.Index(Priorities, priority); level >= 0 {
+		return level
+	}
+	return slices.Index(Priorities, PriorityNormal)
+}
+
+// Recipient получатель рассылки
+type Recipient struct {
+	UUID    string `json:"uuid,omitempty"`    // уведомление-доставка этому получателю, назначает сервер
+	Channel string `json:"channel,omitempty"` // e.g., "log", "email", "telegram", "webhook"
+	Address string `json:"address,omitempty"` // адрес получателя в терминах канала
+}
+
+// Форматы шаблонов сообщений
+const (
+	TemplateFormatText = "text" // text/template
+	TemplateFormatHTML = "html" // html/template, значения экранируются
+)
+
+// Template именованный шаблон сообщения; повторная регистрация имени создаёт новую версию,
+// а уведомление хранит версию, с которой было создано
+type Template struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Format    string    `json:"format"`
+	Subject   string    `json:"subject,omitempty"`
+	Body      string    `json:"body"`
+	Variables []string  `json:"variables,omitempty"` // обязательные переменные data
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListFilter параметры выборки GET /notify
+type ListFilter struct {
+	Status    string
+	From, To  time.Time // окно fire_at, нулевое значение – без границы
+	Recipient string
+	Tag       string
+	Limit     int
+	Cursor    string // непрозрачный курсор из предыдущей страницы
+}
+
+// ScheduledMessage сообщение очереди, отложенное планировщиком до наступления времени
+type ScheduledMessage struct {
+	UUID     string
+	Version  int
+	Priority string
+}
+
+// OutboxEntry уведомление, ожидающее публикации в очередь
+type OutboxEntry struct {
+	UUID  string
+	DueAt time.Time // время, с которого relay может публиковать запись
+}
+
+// Attempt запись о неудачной попытке доставки
+type Attempt struct {
+	Number int       `json:"number"`
+	At     time.Time `json:"at"`
+	Error  string    `json:"error"`
+}
+
+// StatusChange запись истории статусов уведомления
+type StatusChange struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	// Reason причина, по которой worker отложил доставку, и новое время срабатывания
+	Reason string    `json:"reason,omitempty"`
+	Until  time.Time `json:"until,omitzero"`
+}
+
+// StatusEvent смена статуса уведомления в потоке событий GET /notify/events
+type StatusEvent struct {
+	ID         string `json:"id"` // ID записи в потоке, по нему поток возобновляется (Last-Event-ID)
+	UUID       string `json:"uuid"`
+	ParentUUID string `json:"parent_uuid,omitempty"`
+	StatusChange
+	Tags   []string `json:"tags,omitempty"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// CallbackStatuses статусы, о переходе в которые сообщается на callback_url уведомления
+var CallbackStatuses = []string{StatusSent, StatusFailed, StatusCancelled, StatusRetrying}
+
+// CallbackEvent событие смены статуса, отправляемое на callback_url уведомления
+type CallbackEvent struct {
+	ID     string    `json:"id"`   // уникален для события: по нему получатель отбрасывает повторы
+	UUID   string    `json:"uuid"` // уведомление
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+	// Attempts неудачные попытки отправки события
+	Attempts int `json:"attempts"`
+}
+
+// CallbackEntry событие в очереди отправки callback-ов
+type CallbackEntry struct {
+	Event CallbackEvent
+	Key   string    // запись события в хранилище
+	DueAt time.Time // время следующей попытки отправки
+}
+
+// Причины, по которым worker откладывает доставку
+const (
+	ReasonQuietHours = "quiet_hours" // тихие часы получателя
+	ReasonRateLimit  = "rate_limit"  // исчерпан лимит канала или получателя
+)
+
+// QuietWindow ежедневный интервал тишины по местному времени получателя, например 22:00–08:00;
+// окно, у которого End раньше Start, переходит через полночь
+type QuietWindow struct {
+	Start string `json:"start"` // "15:04"
+	End   string `json:"end"`
+}
+
+// Preferences настройки получателя канала: тихие часы в его часовом поясе
+type Preferences struct {
+	Channel    string        `json:"channel"`
+	Address    string        `json:"address"`
+	Timezone   string        `json:"timezone"`
+	QuietHours []QuietWindow `json:"quiet_hours"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// DeadLetter окончательно неудачное уведомление из очереди messageDeadQueue
+type DeadLetter struct {
+	Notification
+	FailedAt time.Time `json:"failed_at"`
+	History  []Attempt `json:"history"`
+}
+
+// IdempotencyRecord сохранённый ответ на POST /notify с заголовком Idempotency-Key
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`           // sha256 тела запроса
+	StatusCode  int    `json:"status_code,omitempty"` // 0 – запрос ещё выполняется
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body,omitempty"`
 }
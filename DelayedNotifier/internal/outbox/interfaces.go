@@ -0,0 +1,24 @@
+package outbox
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"time"
+)
+
+// Store interface for Redis operations used by the relay and the reconciler
+type Store interface {
+	GetMessage(ctx context.Context, uuid string) (models.Notification, error)
+	GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error)
+	TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error)
+	DueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxEntry, error)
+	AckOutbox(ctx context.Context, entry models.OutboxEntry) error
+	DelayOutbox(ctx context.Context, entry models.OutboxEntry, until time.Time) error
+	EnqueueOutbox(ctx context.Context, uuid string, at time.Time) error
+	StaleMessages(ctx context.Context, status string, before time.Time, limit int) ([]string, error)
+}
+
+// Queue interface for RabbitMQ operations used by the relay
+type Queue interface {
+	SendMessage(notification models.Notification) error
+}
@@ -0,0 +1,260 @@
+package outbox
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mock Store для тестирования: уведомления и outbox в памяти
+type MockStore struct {
+	notifications map[string]models.Notification
+	history       map[string][]models.StatusChange
+	outbox        map[string]time.Time
+	// enqueued время и число постановок в outbox через EnqueueOutbox
+	enqueued map[string]time.Time
+	enqueues map[string]int
+}
+
+func newMockStore(notifications ...models.Notification) *MockStore {
+	m := &MockStore{
+		notifications: map[string]models.Notification{},
+		history:       map[string][]models.StatusChange{},
+		outbox:        map[string]time.Time{},
+		enqueued:      map[string]time.Time{},
+		enqueues:      map[string]int{},
+	}
+	for _, n := range notifications {
+		m.notifications[n.UUID] = n
+	}
+	return m
+}
+
+func (m *MockStore) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	n, ok := m.notifications[uuid]
+	if !ok {
+		return models.Notification{}, models.ErrNotFound
+	}
+	return n, nil
+}
+
+func (m *MockStore) GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error) {
+	return m.history[uuid], nil
+}
+
+func (m *MockStore) TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+	n, ok := m.notifications[uuid]
+	if !ok {
+		return "", models.ErrNotFound
+	}
+	for _, status := range from {
+		if n.Status == status {
+			n.Status = to
+			m.notifications[uuid] = n
+			return status, nil
+		}
+	}
+	return n.Status, models.ErrStatusConflict
+}
+
+func (m *MockStore) DueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxEntry, error) {
+	var entries []models.OutboxEntry
+	for uuid, dueAt := range m.outbox {
+		if !dueAt.After(now) && len(entries) < limit {
+			entries = append(entries, models.OutboxEntry{UUID: uuid, DueAt: dueAt})
+		}
+	}
+	return entries, nil
+}
+
+func (m *MockStore) AckOutbox(ctx context.Context, entry models.OutboxEntry) error {
+	if m.outbox[entry.UUID].Equal(entry.DueAt) {
+		delete(m.outbox, entry.UUID)
+	}
+	return nil
+}
+
+func (m *MockStore) DelayOutbox(ctx context.Context, entry models.OutboxEntry, until time.Time) error {
+	if m.outbox[entry.UUID].Equal(entry.DueAt) {
+		m.outbox[entry.UUID] = until
+	}
+	return nil
+}
+
+func (m *MockStore) EnqueueOutbox(ctx context.Context, uuid string, at time.Time) error {
+	m.enqueued[uuid] = at
+	m.enqueues[uuid]++
+	if _, ok := m.outbox[uuid]; !ok {
+		m.outbox[uuid] = at
+	}
+	return nil
+}
+
+func (m *MockStore) StaleMessages(ctx context.Context, status string, before time.Time, limit int) ([]string, error) {
+	var uuids []string
+	for uuid, n := range m.notifications {
+		if n.Status == status && n.FireAt.Before(before) && m.enqueued[uuid].Before(before) && len(uuids) < limit {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids, nil
+}
+
+// Mock Queue для тестирования; relay публикует из нескольких горутин
+type MockQueue struct {
+	mu   sync.Mutex
+	err  error
+	sent []models.Notification
+}
+
+func (m *MockQueue) SendMessage(notification models.Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+func TestRelay_Flush(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name              string
+		notification      *models.Notification
+		queueErr          error
+		expectedPublished int
+		expectedInOutbox  bool
+	}{
+		{
+			name:              "Pending notification is published",
+			notification:      &models.Notification{UUID: "n-1", Status: models.StatusPending, FireAt: now.Add(time.Minute)},
+			expectedPublished: 1,
+		},
+		{
+			name:              "Overdue notification is published without delay",
+			notification:      &models.Notification{UUID: "n-1", Status: models.StatusRetrying, FireAt: now.Add(-time.Minute)},
+			expectedPublished: 1,
+		},
+		{
+			name:             "Cancelled notification is dropped",
+			notification:     &models.Notification{UUID: "n-1", Status: models.StatusCancelled, FireAt: now.Add(time.Minute)},
+			expectedInOutbox: false,
+		},
+		{
+			name:             "Deleted notification is dropped",
+			expectedInOutbox: false,
+		},
+		{
+			name:             "Queue failure keeps the entry",
+			notification:     &models.Notification{UUID: "n-1", Status: models.StatusPending, FireAt: now.Add(time.Minute)},
+			queueErr:         errors.New("queue connection failed"),
+			expectedInOutbox: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMockStore()
+			if tt.notification != nil {
+				store.notifications["n-1"] = *tt.notification
+			}
+			store.outbox["n-1"] = now.Add(-time.Second)
+			queue := &MockQueue{err: tt.queueErr}
+			relay := NewRelay(store, queue, time.Second, 10, time.Minute, 4)
+
+			published, err := relay.Flush(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if published != tt.expectedPublished {
+				t.Errorf("Expected %d published, got %d", tt.expectedPublished, published)
+			}
+			dueAt, inOutbox := store.outbox["n-1"]
+			if inOutbox != tt.expectedInOutbox {
+				t.Errorf("Expected entry in outbox: %v, got %v", tt.expectedInOutbox, inOutbox)
+			}
+			// неудачная публикация повторяется через RetryDelay
+			if tt.queueErr != nil && !dueAt.After(now) {
+				t.Errorf("Expected entry to be delayed, due at %s", dueAt)
+			}
+			for _, n := range queue.sent {
+				delay := time.Duration(n.ScheduledAt) * time.Millisecond
+				if expected := time.Until(tt.notification.FireAt); delay < expected-time.Second || delay > max(expected, 0) {
+					t.Errorf("Expected delay about %s, got %s", expected, delay)
+				}
+			}
+		})
+	}
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	now := time.Now()
+	store := newMockStore(
+		models.Notification{UUID: "lost", Status: models.StatusPending, FireAt: now.Add(-time.Hour)},
+		models.Notification{UUID: "recent", Status: models.StatusPending, FireAt: now.Add(-time.Second)},
+		models.Notification{UUID: "future", Status: models.StatusPending, FireAt: now.Add(time.Hour)},
+		models.Notification{UUID: "stuck", Status: models.StatusProcessing, FireAt: now.Add(-time.Hour)},
+		models.Notification{UUID: "late-claim", Status: models.StatusProcessing, FireAt: now.Add(-time.Hour)},
+		models.Notification{UUID: "sent", Status: models.StatusSent, FireAt: now.Add(-time.Hour)},
+	)
+	store.history["stuck"] = []models.StatusChange{{Status: models.StatusProcessing, At: now.Add(-time.Hour)}}
+	store.history["late-claim"] = []models.StatusChange{{Status: models.StatusProcessing, At: now.Add(-time.Second)}}
+
+	reconciler := NewReconciler(store, time.Minute, time.Minute, 10*time.Minute)
+	restored, err := reconciler.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored != 2 {
+		t.Errorf("Expected 2 restored notifications, got %d", restored)
+	}
+	for _, uuid := range []string{"lost", "stuck"} {
+		if _, ok := store.outbox[uuid]; !ok {
+			t.Errorf("Expected %s in outbox", uuid)
+		}
+	}
+	for _, uuid := range []string{"recent", "future", "late-claim", "sent"} {
+		if _, ok := store.outbox[uuid]; ok {
+			t.Errorf("Unexpected %s in outbox", uuid)
+		}
+	}
+	if status := store.notifications["stuck"].Status; status != models.StatusRetrying {
+		t.Errorf("Expected stuck notification to be retrying, got %s", status)
+	}
+	if status := store.notifications["late-claim"].Status; status != models.StatusProcessing {
+		t.Errorf("Expected late claimed notification to stay processing, got %s", status)
+	}
+}
+
+func TestReconciler_LaggingBacklog(t *testing.T) {
+	now := time.Now()
+	var backlog []models.Notification
+	for i := range 3 {
+		backlog = append(backlog, models.Notification{UUID: fmt.Sprint("lagging-", i), Status: models.StatusPending, FireAt: now.Add(-time.Hour)})
+	}
+	store := newMockStore(backlog...)
+	relay := NewRelay(store, &MockQueue{}, time.Second, 10, time.Second, 1)
+	// пачка меньше backlog: второй проход должен дойти до уведомлений, не попавших в первый
+	reconciler := NewReconciler(store, time.Minute, time.Minute, 10*time.Minute)
+
+	for range 2 {
+		if _, err := reconciler.reconcile(context.Background(), 2); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// relay публикует возвращённые уведомления, consumer-ы их ещё не обработали
+		if _, err := relay.Flush(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	for _, n := range backlog {
+		if store.enqueues[n.UUID] != 1 {
+			t.Errorf("Expected %s to be enqueued once, got %d", n.UUID, store.enqueues[n.UUID])
+		}
+	}
+}
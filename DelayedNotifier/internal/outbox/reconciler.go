@@ -0,0 +1,133 @@
+package outbox
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// reconcileBatch число уведомлений одного статуса, проверяемых за проход
+const reconcileBatch = 500
+
+// Reconciler восстанавливает согласованность Redis и очереди после сбоев.
+// Ожидающее уведомление, чьё время прошло больше Grace назад, считается потерявшим сообщение
+// в очереди и возвращается в outbox; уведомление, захваченное worker-ом дольше ProcessingTimeout
+// назад, считается брошенным упавшим worker-ом и переводится в retrying для повторной доставки.
+// Возвращённое в outbox уведомление снова считается потерянным не раньше, чем через Grace после
+// возврата: при отставании consumer-ов одно и то же уведомление не публикуется каждый проход.
+// Обратный случай – сообщение в очереди без записи – не требует действий: worker
+// подтверждает такое сообщение без обработки.
+type Reconciler struct {
+	store Store
+
+	Interval          time.Duration
+	Grace             time.Duration
+	ProcessingTimeout time.Duration
+}
+
+func NewReconciler(store Store, interval, grace, processingTimeout time.Duration) *Reconciler {
+	return &Reconciler{
+		store:             store,
+		Interval:          interval,
+		Grace:             grace,
+		ProcessingTimeout: processingTimeout,
+	}
+}
+
+// Run выполняет сверку каждые Interval до отмены ctx
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := r.Reconcile(ctx); err != nil {
+				log.Printf("Outbox reconciler: %s", err)
+			}
+		}
+	}
+}
+
+// Reconcile выполняет один проход сверки и возвращает число уведомлений, возвращённых в outbox
+func (r *Reconciler) Reconcile(ctx context.Context) (int, error) {
+	return r.reconcile(ctx, reconcileBatch)
+}
+
+// reconcile проход сверки, проверяющий до batch уведомлений каждого статуса
+func (r *Reconciler) reconcile(ctx context.Context, batch int) (int, error) {
+	now := time.Now()
+	restored := 0
+	// освобождённое уведомление попадает и в выборку retrying, считается оно один раз
+	enqueued := map[string]bool{}
+
+	// брошенные worker-ом уведомления сначала переводятся в retrying, чтобы worker мог захватить их снова
+	stuck, err := r.store.StaleMessages(ctx, models.StatusProcessing, now.Add(-r.ProcessingTimeout), batch)
+	if err != nil {
+		return 0, err
+	}
+	for _, uuid := range stuck {
+		// индекс хранит время срабатывания; захват мог произойти позже, например при очереди сообщений
+		claimed, err := r.claimedAt(ctx, uuid)
+		if err != nil {
+			log.Printf("Failed to get status history of %s: %s", uuid, err)
+			continue
+		}
+		if claimed.After(now.Add(-r.ProcessingTimeout)) {
+			continue
+		}
+		_, err = r.store.TransitionStatus(ctx, uuid, models.StatusRetrying, models.StatusProcessing)
+		if errors.Is(err, models.ErrStatusConflict) || errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to release stuck notification %s: %s", uuid, err)
+			continue
+		}
+		log.Printf("Notification %s is stuck in processing, retry it", uuid)
+		if r.enqueue(ctx, uuid, now) {
+			enqueued[uuid] = true
+			restored++
+		}
+	}
+
+	for _, status := range []string{models.StatusPending, models.StatusRetrying} {
+		overdue, err := r.store.StaleMessages(ctx, status, now.Add(-r.Grace), batch)
+		if err != nil {
+			return restored, err
+		}
+		for _, uuid := range overdue {
+			if !enqueued[uuid] && r.enqueue(ctx, uuid, now) {
+				restored++
+			}
+		}
+	}
+	return restored, nil
+}
+
+// claimedAt время последнего перехода уведомления в processing
+func (r *Reconciler) claimedAt(ctx context.Context, uuid string) (time.Time, error) {
+	history, err := r.store.GetHistory(ctx, uuid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var claimed time.Time
+	for _, change := range history {
+		if change.Status == models.StatusProcessing {
+			claimed = change.At
+		}
+	}
+	return claimed, nil
+}
+
+func (r *Reconciler) enqueue(ctx context.Context, uuid string, now time.Time) bool {
+	if err := r.store.EnqueueOutbox(ctx, uuid, now); err != nil {
+		log.Printf("Failed to enqueue notification %s into outbox: %s", uuid, err)
+		return false
+	}
+	return true
+}
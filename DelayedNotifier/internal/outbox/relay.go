@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// Relay публикует в очередь уведомления из outbox. Запись outbox создаётся вместе
+// с уведомлением, поэтому сбой между сохранением и публикацией не теряет уведомление:
+// неопубликованная запись остаётся в outbox и публикуется повторно через RetryDelay.
+type Relay struct {
+	store Store
+	queue Queue
+
+	PollInterval time.Duration
+	BatchSize    int
+	RetryDelay   time.Duration
+	Concurrency  int // число одновременных публикаций
+}
+
+func NewRelay(store Store, queue Queue, pollInterval time.Duration, batchSize int, retryDelay time.Duration, concurrency int) *Relay {
+	return &Relay{
+		store:        store,
+		queue:        queue,
+		PollInterval: pollInterval,
+		BatchSize:    batchSize,
+		RetryDelay:   retryDelay,
+		Concurrency:  concurrency,
+	}
+}
+
+// Run публикует записи outbox каждые PollInterval до отмены ctx;
+// пока пачки заполнены целиком (например, после POST /notify/batch), следующая публикуется сразу
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			published, err := r.Flush(ctx)
+			if err != nil {
+				log.Printf("Outbox relay: %s", err)
+			}
+			if err != nil || published < r.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush публикует одну пачку наступивших записей outbox и возвращает число опубликованных.
+// Уведомление, которое уже не ожидает отправки (удалено, отменено, обработано), снимается
+// с outbox без публикации. Пачка публикуется в Concurrency горутинах: подтверждения брокера
+// ожидаются параллельно, а не по одному на сообщение.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	now := time.Now()
+	entries, err := r.store.DueOutbox(ctx, now, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var due []models.OutboxEntry
+	var notifications []models.Notification
+	for _, entry := range entries {
+		notification, err := r.store.GetMessage(ctx, entry.UUID)
+		if errors.Is(err, models.ErrNotFound) {
+			r.ack(ctx, entry)
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to load outbox notification %s: %s", entry.UUID, err)
+			continue
+		}
+		if notification.Status != models.StatusPending && notification.Status != models.StatusRetrying {
+			r.ack(ctx, entry)
+			continue
+		}
+
+		// задержка отсчитывается от сохранённого времени срабатывания, а не от момента создания
+		delay := notification.FireAt.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		notification.ScheduledAt = delay.Milliseconds()
+		due = append(due, entry)
+		notifications = append(notifications, notification)
+	}
+
+	errs := r.publish(notifications)
+
+	published := 0
+	for i, entry := range due {
+		if err := errs[i]; err != nil {
+			log.Printf("Failed to publish outbox notification %s, retry in %s: %s", entry.UUID, r.RetryDelay, err)
+			if err := r.store.DelayOutbox(ctx, entry, now.Add(r.RetryDelay)); err != nil {
+				log.Printf("Failed to delay outbox notification %s: %s", entry.UUID, err)
+			}
+			continue
+		}
+		published++
+		r.ack(ctx, entry)
+	}
+	return published, nil
+}
+
+// publish отправляет уведомления в очередь не более чем в Concurrency горутинах
+// и возвращает ошибку публикации каждого
+func (r *Relay) publish(notifications []models.Notification) []error {
+	errs := make([]error, len(notifications))
+	concurrency := r.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, notification := range notifications {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.queue.SendMessage(notification)
+			<-sem
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func (r *Relay) ack(ctx context.Context, entry models.OutboxEntry) {
+	// неудачное подтверждение приведёт к повторной публикации, которую worker отбросит
+	if err := r.store.AckOutbox(ctx, entry); err != nil {
+		log.Printf("Failed to ack outbox notification %s: %s", entry.UUID, err)
+	}
+}
@@ -0,0 +1,115 @@
+package quiethours
+
+import (
+	"DelayedNotifier/internal/models"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// clockLayout формат границ окна тишины
+	clockLayout = "15:04"
+	// maxWindows предел числа окон тишины одного получателя
+	maxWindows = 10
+)
+
+// Validate проверяет часовой пояс и окна тишины
+func Validate(p models.Preferences) error {
+	if p.Address == "" {
+		return errors.New("address is required")
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "" {
+		return fmt.Errorf("unknown timezone %q", p.Timezone)
+	}
+	if len(p.QuietHours) > maxWindows {
+		return fmt.Errorf("at most %d quiet windows are allowed", maxWindows)
+	}
+	for i, window := range p.QuietHours {
+		start, err := parseClock(window.Start)
+		if err != nil {
+			return fmt.Errorf("quiet window %d: invalid start: %w", i, err)
+		}
+		end, err := parseClock(window.End)
+		if err != nil {
+			return fmt.Errorf("quiet window %d: invalid end: %w", i, err)
+		}
+		if start == end {
+			return fmt.Errorf("quiet window %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// Until возвращает конец тихих часов, в которые попадает now по времени получателя;
+// ok – false, если сейчас не тихие часы. Если конец окна попадает в другое окно,
+// возвращается конец последнего из них. Окна, покрывающие все сутки, откладывают
+// доставку не больше чем на число окон переходов.
+func Until(p models.Preferences, now time.Time) (until time.Time, ok bool) {
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	local := now.In(location)
+
+	until = local
+	for range len(p.QuietHours) {
+		end, in := latestEnd(p.QuietHours, until)
+		if !in {
+			break
+		}
+		until = end
+	}
+	return until, until.After(local)
+}
+
+// latestEnd самый поздний конец окон, в которые попадает local
+func latestEnd(windows []models.QuietWindow, local time.Time) (time.Time, bool) {
+	var latest time.Time
+	for _, window := range windows {
+		if end, in := windowEnd(window, local); in && end.After(latest) {
+			latest = end
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// windowEnd возвращает конец окна, если local попадает в окно
+func windowEnd(window models.QuietWindow, local time.Time) (time.Time, bool) {
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := parseClock(window.End)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	clock := local.Hour()*60 + local.Minute()
+	var in bool
+	if start < end {
+		in = clock >= start && clock < end
+	} else {
+		// окно через полночь
+		in = clock >= start || clock < end
+	}
+	if !in {
+		return time.Time{}, false
+	}
+
+	year, month, day := local.Date()
+	until := time.Date(year, month, day, end/60, end%60, 0, 0, local.Location())
+	if !until.After(local) {
+		until = time.Date(year, month, day+1, end/60, end%60, 0, 0, local.Location())
+	}
+	return until, true
+}
+
+// parseClock переводит "15:04" в минуты от полуночи
+func parseClock(value string) (int, error) {
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
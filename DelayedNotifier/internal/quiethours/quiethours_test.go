@@ -0,0 +1,113 @@
+package quiethours
+
+import (
+	"DelayedNotifier/internal/models"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefs     models.Preferences
+		expectErr bool
+	}{
+		{
+			name:  "Overnight window",
+			prefs: models.Preferences{Address: "a", Timezone: "Europe/Moscow", QuietHours: []models.QuietWindow{{Start: "22:00", End: "08:00"}}},
+		},
+		{
+			name:      "Unknown timezone",
+			prefs:     models.Preferences{Address: "a", Timezone: "Mars/Olympus"},
+			expectErr: true,
+		},
+		{
+			name:      "Invalid clock",
+			prefs:     models.Preferences{Address: "a", Timezone: "UTC", QuietHours: []models.QuietWindow{{Start: "25:00", End: "08:00"}}},
+			expectErr: true,
+		},
+		{
+			name:      "Empty window",
+			prefs:     models.Preferences{Address: "a", Timezone: "UTC", QuietHours: []models.QuietWindow{{Start: "08:00", End: "08:00"}}},
+			expectErr: true,
+		},
+		{
+			name:      "Missing address",
+			prefs:     models.Preferences{Timezone: "UTC"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.prefs); (err != nil) != tt.expectErr {
+				t.Errorf("Expected error: %v, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestUntil(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	prefs := models.Preferences{
+		Timezone: "Europe/Moscow",
+		QuietHours: []models.QuietWindow{
+			{Start: "22:00", End: "08:00"},
+			{Start: "13:00", End: "14:00"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		windows  []models.QuietWindow
+		expected time.Time // нулевое – не тихие часы
+	}{
+		{
+			name:     "Evening part of overnight window",
+			now:      time.Date(2026, 3, 10, 23, 30, 0, 0, moscow),
+			expected: time.Date(2026, 3, 11, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:     "Morning part of overnight window",
+			now:      time.Date(2026, 3, 11, 7, 59, 30, 0, moscow),
+			expected: time.Date(2026, 3, 11, 8, 0, 0, 0, moscow),
+		},
+		{
+			name: "Outside of windows",
+			now:  time.Date(2026, 3, 11, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:     "Daytime window",
+			now:      time.Date(2026, 3, 11, 13, 15, 0, 0, moscow),
+			expected: time.Date(2026, 3, 11, 14, 0, 0, 0, moscow),
+		},
+		{
+			name:     "Time zone of the recipient is used",
+			now:      time.Date(2026, 3, 11, 20, 0, 0, 0, time.UTC), // 23:00 в Москве
+			expected: time.Date(2026, 3, 12, 8, 0, 0, 0, moscow),
+		},
+		{
+			name:     "Chained windows",
+			now:      time.Date(2026, 3, 11, 23, 0, 0, 0, moscow),
+			windows:  []models.QuietWindow{{Start: "22:00", End: "08:00"}, {Start: "07:00", End: "09:30"}},
+			expected: time.Date(2026, 3, 12, 9, 30, 0, 0, moscow),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := prefs
+			if tt.windows != nil {
+				p.QuietHours = tt.windows
+			}
+			until, ok := Until(p, tt.now)
+			if ok != !tt.expected.IsZero() {
+				t.Fatalf("Expected quiet: %v, got %v (until %s)", !tt.expected.IsZero(), ok, until)
+			}
+			if ok && !until.Equal(tt.expected) {
+				t.Errorf("Expected until %s, got %s", tt.expected, until)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package rabbitMQ
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrNotConnected возвращается при публикации, пока соединение с брокером восстанавливается
+var ErrNotConnected = errors.New("broker connection is not established")
+
+// ErrNacked возвращается, если брокер не подтвердил публикацию
+var ErrNacked = errors.New("message is not confirmed by broker")
+
+// Connection держит соединение с брокером и восстанавливает его после обрыва: переподключается
+// с растущей задержкой от MinDelay до MaxDelay и заново объявляет топологию.
+// Публикация идёт через отдельный канал в режиме подтверждений (publisher confirms).
+type Connection struct {
+	url      string
+	delayed  bool
+	MinDelay time.Duration
+	MaxDelay time.Duration
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	publisher *amqp.Channel
+	// ready закрывается после установки соединения и заменяется при обрыве
+	ready chan struct{}
+}
+
+// Dial подключается к брокеру, повторяя попытки до успеха или отмены ctx,
+// и следит за соединением до отмены ctx. delayed – объявлять ли DelayedExchange
+// (нужен плагин rabbitmq-delayed-message-exchange).
+func Dial(ctx context.Context, url string, delayed bool, minDelay, maxDelay time.Duration) (*Connection, error) {
+	c := &Connection{
+		url:      url,
+		delayed:  delayed,
+		MinDelay: minDelay,
+		MaxDelay: maxDelay,
+		ready:    make(chan struct{}),
+	}
+
+	closed, err := c.reconnect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	go c.watch(ctx, closed)
+
+	return c, nil
+}
+
+// watch ждёт обрыва соединения или канала публикации и переподключается
+func (c *Connection) watch(ctx context.Context, closed <-chan *amqp.Error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case amqpErr := <-closed:
+			// соединение закрывается и при остановке сервиса
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Broker connection is lost: %v", amqpErr)
+		}
+
+		c.mu.Lock()
+		c.publisher = nil
+		c.ready = make(chan struct{})
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		c.mu.Unlock()
+
+		var err error
+		closed, err = c.reconnect(ctx)
+		if err != nil {
+			return
+		}
+		log.Printf("Broker connection is restored")
+	}
+}
+
+// reconnect подключается с растущей задержкой; ошибка возвращается только при отмене ctx
+func (c *Connection) reconnect(ctx context.Context) (<-chan *amqp.Error, error) {
+	for attempt := 0; ; attempt++ {
+		closed, err := c.connect()
+		if err == nil {
+			return closed, nil
+		}
+
+		delay := backoff(attempt, c.MinDelay, c.MaxDelay)
+		log.Printf("Failed to connect to broker, retry in %s: %s", delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// connect открывает соединение, объявляет топологию и канал публикации в режиме подтверждений.
+// Возвращаемый канал получает ошибку при обрыве соединения или канала публикации.
+func (c *Connection) connect() (<-chan *amqp.Error, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := declareTopology(ch, c.delayed); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to declare topology: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	closed := make(chan *amqp.Error, 2)
+	conn.NotifyClose(forward(closed))
+	ch.NotifyClose(forward(closed))
+
+	c.mu.Lock()
+	c.conn = conn
+	c.publisher = ch
+	close(c.ready)
+	c.mu.Unlock()
+
+	return closed, nil
+}
+
+// forward передаёт первую ошибку закрытия в общий канал. Библиотека закрывает
+// канал уведомлений при закрытии, поэтому каждому источнику нужен свой.
+func forward(closed chan<- *amqp.Error) chan *amqp.Error {
+	notify := make(chan *amqp.Error, 1)
+	go func() {
+		amqpErr, ok := <-notify
+		if !ok {
+			amqpErr = amqp.ErrClosed
+		}
+		select {
+		case closed <- amqpErr:
+		default:
+		}
+	}()
+	return notify
+}
+
+// Publish публикует сообщение и ждёт подтверждения брокера
+func (c *Connection) Publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	c.mu.RLock()
+	publisher := c.publisher
+	c.mu.RUnlock()
+	if publisher == nil {
+		return ErrNotConnected
+	}
+
+	confirmation, err := publisher.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, msg)
+	if err != nil {
+		return err
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// Channel открывает новый канал, дожидаясь восстановления соединения
+func (c *Connection) Channel(ctx context.Context) (*amqp.Channel, error) {
+	c.mu.RLock()
+	ready := c.ready
+	c.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-ready:
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn.Channel()
+}
+
+func (c *Connection) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+}
+
+// backoff задержка перед попыткой attempt (с нуля): MinDelay, удваивается до MaxDelay
+func backoff(attempt int, minDelay, maxDelay time.Duration) time.Duration {
+	delay := minDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
@@ -0,0 +1,48 @@
+package rabbitMQ
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{attempt: 0, expected: time.Second},
+		{attempt: 1, expected: 2 * time.Second},
+		{attempt: 3, expected: 8 * time.Second},
+		{attempt: 5, expected: 30 * time.Second},
+		{attempt: 100, expected: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if delay := backoff(tt.attempt, time.Second, 30*time.Second); delay != tt.expected {
+			t.Errorf("Attempt %d: expected %s, got %s", tt.attempt, tt.expected, delay)
+		}
+	}
+}
+
+func TestDeliveryVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  amqp.Table
+		expected int
+	}{
+		{name: "int64 header", headers: amqp.Table{VersionHeader: int64(3)}, expected: 3},
+		{name: "int32 header", headers: amqp.Table{VersionHeader: int32(2)}, expected: 2},
+		{name: "Missing header", headers: amqp.Table{}, expected: -1},
+		{name: "Unexpected type", headers: amqp.Table{VersionHeader: "1"}, expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if version := deliveryVersion(amqp.Delivery{Headers: tt.headers}); version != tt.expected {
+				t.Errorf("Expected version %d, got %d", tt.expected, version)
+			}
+		})
+	}
+}
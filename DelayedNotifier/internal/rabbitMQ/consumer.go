@@ -0,0 +1,143 @@
+package rabbitMQ
+
+import (
+	"DelayedNotifier/internal/worker"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// HandlerFunc обрабатывает одно сообщение из очереди: UUID уведомления из тела
+// и версию расписания из VersionHeader (-1, если заголовка нет).
+type HandlerFunc func(ctx context.Context, uuid string, version int) error
+
+// resubscribeDelay пауза перед повторной подпиской после обрыва канала
+const resubscribeDelay = time.Second
+
+type Consumer struct {
+	Conn     *Connection
+	Queue    string
+	Prefetch int
+}
+
+func NewConsumer(conn *Connection, queue string, prefetch int) *Consumer {
+	return &Consumer{
+		Conn:     conn,
+		Queue:    queue,
+		Prefetch: prefetch,
+	}
+}
+
+// Run читает очередь с ручным подтверждением в workers горутинах и блокируется до отмены ctx.
+// Успешно обработанное сообщение подтверждается (Ack). worker.ErrDeadLetter отклоняет
+// сообщение сразу, при другой ошибке оно возвращается в очередь один раз, а повторная
+// ошибка отклоняет его окончательно. Отклонённые сообщения уходят в dead-letter exchange очереди.
+// После обрыва соединения consumer подписывается заново, когда Connection его восстановит;
+// неподтверждённые сообщения брокер вернёт в очередь сам.
+func (c *Consumer) Run(ctx context.Context, workers int, handle HandlerFunc) error {
+	for {
+		err := c.run(ctx, workers, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("Consumer of %s is interrupted, resubscribe: %s", c.Queue, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// run обрабатывает сообщения одного канала до его закрытия
+func (c *Consumer) run(ctx context.Context, workers int, handle HandlerFunc) error {
+	ch, err := c.Conn.Channel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	// prefetch ограничивает число неподтверждённых сообщений на консьюмере
+	if err := ch.Qos(c.Prefetch, 0, false); err != nil {
+		return err
+	}
+
+	deliveries, err := ch.ConsumeWithContext(
+		ctx,
+		c.Queue,
+		"",
+		false, // manual ack
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- c.consume(ctx, deliveries, handle)
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+
+	return <-errCh
+}
+
+func (c *Consumer) consume(ctx context.Context, deliveries <-chan amqp.Delivery, handle HandlerFunc) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("delivery channel is closed")
+			}
+
+			if err := handle(ctx, string(d.Body), deliveryVersion(d)); err != nil {
+				log.Printf("Failed to handle message %s: %s", d.Body, err)
+				requeue := !d.Redelivered && !errors.Is(err, worker.ErrDeadLetter)
+				if err := d.Nack(false, requeue); err != nil {
+					log.Printf("Failed to nack message: %s", err)
+				}
+				continue
+			}
+
+			if err := d.Ack(false); err != nil {
+				log.Printf("Failed to ack message: %s", err)
+			}
+		}
+	}
+}
+
+// deliveryVersion читает версию расписания; сообщения, опубликованные до её появления, получают -1
+func deliveryVersion(d amqp.Delivery) int {
+	switch version := d.Headers[VersionHeader].(type) {
+	case int64:
+		return int(version)
+	case int32:
+		return int(version)
+	case int:
+		return version
+	default:
+		return -1
+	}
+}
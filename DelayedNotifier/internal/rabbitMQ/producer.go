@@ -9,42 +9,47 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// VersionHeader заголовок с версией расписания уведомления (models.Notification.Version)
+const VersionHeader = "x-schedule-version"
+
 type QueueProps struct {
-	Channel         *amqp.Channel
+	Conn            *Connection
 	WaitingExchange string
 	RoutingKey      string
 	// Add Redis DataBase... to storage
 }
 
-func NewQueueProps(ch *amqp.Channel, args ...string) *QueueProps {
+func NewQueueProps(conn *Connection, args ...string) *QueueProps {
 	return &QueueProps{
-		Channel:         ch,
+		Conn:            conn,
 		WaitingExchange: args[0],
 		RoutingKey:      args[1],
 	}
 }
 
 // SendMessage publishes a message to the specified queue.
+// Приоритет сообщения – приоритет уведомления, delayed exchange сохраняет его до WorkQueue.
+// Успех возвращается только после подтверждения публикации брокером.
 func (qp *QueueProps) SendMessage(notification models.Notification) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	//millisecondsDelay := time.Duration(notification.ScheduledAt) * time.Millisecond
 	headers := amqp.Table{
-		"x-delay": int64(notification.ScheduledAt),
+		"x-delay":     int64(notification.ScheduledAt),
+		VersionHeader: int64(notification.Version),
 	}
 	// Хранить в очереди будем только message UUID поле.
-	err := qp.Channel.PublishWithContext(
+	err := qp.Conn.Publish(
 		ctx,
 		qp.WaitingExchange,
 		qp.RoutingKey,
-		false,
-		false,
 		amqp.Publishing{
 			DeliveryMode: amqp.Persistent,
 			ContentType:  "text/plain",
 			Body:         []byte(notification.UUID),
 			Headers:      headers,
+			Priority:     uint8(models.PriorityLevel(notification.Priority)),
 		},
 	)
 
@@ -56,12 +61,26 @@ func (qp *QueueProps) SendMessage(notification models.Notification) error {
 	return nil
 }
 
+// MaxDelay задержку x-delay держит плагин delayed exchange, её потолок – models.MaxDelay;
+// при публикации прямо в очередь (без delayed exchange) задержки нет
+func (qp *QueueProps) MaxDelay() time.Duration {
+	if qp.WaitingExchange == DelayedExchange {
+		return models.MaxDelay
+	}
+	return 0
+}
+
 func (qp *QueueProps) GetMessageStatus(messageId string) (models.Notification, error) {
 	// TODO: to implement get status of certain message
 
 	return models.Notification{}, nil
 }
 
-func (qp *QueueProps) CancelMessageDelay(messageId string) (models.Notification, error) {
-	return models.Notification{}, nil
+// CancelMessageDelay вызывается после отмены уведомления в хранилище.
+// Сообщение, уже лежащее в delayedExchange, удалить нельзя: оно дойдёт до consumer-а,
+// который пропустит уведомление в статусе cancelled. Поэтому здесь достаточно
+// зафиксировать отмену в логе.
+func (qp *QueueProps) CancelMessageDelay(messageId string) error {
+	log.Printf("Notification %s is cancelled, its delayed message will be skipped by consumer", messageId)
+	return nil
 }
@@ -0,0 +1,114 @@
+package rabbitMQ
+
+import (
+	"DelayedNotifier/internal/models"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Топология брокера: отложенные сообщения публикуются в DelayedExchange и после задержки
+// попадают в WorkQueue; отклонённые consumer-ом сообщения уходят через DeadLetterExchange в DeadQueue.
+const (
+	DelayedExchange    = "delayedExchange"
+	RoutingKey         = "my_routing_key"
+	WorkQueue          = "messageMainQueue"
+	DeadLetterExchange = "deadLetterExchange"
+	DeadQueue          = "messageDeadQueue"
+	DeadRoutingKey     = "dead"
+)
+
+// MaxPriority наибольший приоритет сообщения WorkQueue (x-max-priority): приоритет сообщения –
+// models.PriorityLevel уведомления, брокер отдаёт consumer-ам сначала более приоритетные сообщения
+var MaxPriority = len(models.Priorities) - 1
+
+// declareTopology объявляет обменники и очереди; объявление идемпотентно и повторяется
+// после каждого переподключения к брокеру. Без delayed DelayedExchange не объявляется:
+// задержку держит планировщик вне брокера, а сообщения публикуются прямо в WorkQueue,
+// поэтому плагин rabbitmq-delayed-message-exchange не нужен.
+func declareTopology(ch *amqp.Channel, delayed bool) error {
+	// обменник и очередь окончательно неудачных уведомлений
+	err := ch.ExchangeDeclare(
+		DeadLetterExchange,
+		"direct",
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = ch.QueueDeclare(
+		DeadQueue,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = ch.QueueBind(
+		DeadQueue,
+		DeadRoutingKey,
+		DeadLetterExchange,
+		false,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	// основная очередь для передачи сообщений на обработку в consumer;
+	// отклонённые consumer-ом сообщения уходят в DeadLetterExchange, сообщения
+	// с большим приоритетом выдаются раньше.
+	// Аргументы очереди нельзя поменять у существующей очереди: messageMainQueue,
+	// созданную без них (в том числе без x-max-priority), нужно удалить перед запуском.
+	_, err = ch.QueueDeclare(
+		WorkQueue,
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-dead-letter-exchange":    DeadLetterExchange,
+			"x-dead-letter-routing-key": DeadRoutingKey,
+			"x-max-priority":            int32(MaxPriority),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if !delayed {
+		return nil
+	}
+
+	// Объявление контроллера отложенных сообщений
+	err = ch.ExchangeDeclare(
+		DelayedExchange,
+		"x-delayed-message", // type to delayed messages
+		true,
+		false,
+		false,
+		false,
+		amqp.Table{
+			"x-delayed-type": "direct",
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ch.QueueBind(
+		WorkQueue,
+		RoutingKey,
+		DelayedExchange,
+		false,
+		nil,
+	)
+}
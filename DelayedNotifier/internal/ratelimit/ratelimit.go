@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Bucket token bucket: Rate токенов в секунду, ёмкость Burst; доставка забирает один токен
+type Bucket struct {
+	Key   string
+	Rate  float64
+	Burst int
+}
+
+// Available число токенов корзины к моменту now, если в момент at их было tokens
+func (b Bucket) Available(tokens float64, at, now time.Time) float64 {
+	if elapsed := now.Sub(at); elapsed > 0 {
+		tokens += elapsed.Seconds() * b.Rate
+	}
+	return math.Min(tokens, float64(b.Burst))
+}
+
+// Wait время, через которое в корзине с tokens токенами появится целый токен
+func (b Bucket) Wait(tokens float64) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil((1 - tokens) / b.Rate * float64(time.Second)))
+}
+
+// Store хранит состояние корзин
+type Store interface {
+	// TakeTokens забирает по токену из всех корзин, если токены есть в каждой, и возвращает 0;
+	// иначе ничего не забирает и возвращает время до появления токенов во всех корзинах
+	TakeTokens(ctx context.Context, buckets []Bucket, now time.Time) (time.Duration, error)
+}
+
+// Limiter ограничивает частоту доставок по каналу и получателю
+type Limiter struct {
+	store Store
+	cfg   config.RateLimit
+}
+
+func New(store Store, cfg config.RateLimit) *Limiter {
+	return &Limiter{store: store, cfg: cfg}
+}
+
+// Reserve забирает токены для доставки уведомления. Если лимит исчерпан, возвращает задержку,
+// после которой доставку стоит повторить: время до появления токена плюс случайная добавка
+// до того же времени, чтобы отложенные одновременно доставки не вернулись все разом.
+func (l *Limiter) Reserve(ctx context.Context, notification models.Notification) (time.Duration, error) {
+	buckets := l.buckets(notification)
+	if len(buckets) == 0 {
+		return 0, nil
+	}
+
+	wait, err := l.store.TakeTokens(ctx, buckets, time.Now())
+	if err != nil || wait <= 0 {
+		return 0, err
+	}
+	return wait + rand.N(wait+1), nil
+}
+
+// buckets корзины, из которых доставка уведомления забирает токены
+func (l *Limiter) buckets(notification models.Notification) []Bucket {
+	channel := notification.Channel
+	if channel == "" {
+		channel = models.ChannelLog
+	}
+	tenant := l.cfg.Tenants[notification.Tenant]
+
+	var buckets []Bucket
+	if bucket, ok := bucketOf(l.cfg.Channel, tenant.Channel, notification.Tenant, channel, "channel:"+channel); ok {
+		buckets = append(buckets, bucket)
+	}
+	if bucket, ok := bucketOf(l.cfg.Recipient, tenant.Recipient, notification.Tenant, channel, "recipient:"+channel+":"+notification.Recipient); ok {
+		buckets = append(buckets, bucket)
+	}
+	return buckets
+}
+
+// bucketOf выбирает лимит tenant-а, если он задан, иначе общий; ok – false, если лимита нет
+func bucketOf(global, override map[string]config.Limit, tenant, channel, key string) (Bucket, bool) {
+	limit, ok := override[channel]
+	if ok {
+		key = "tenant:" + tenant + ":" + key
+	} else {
+		limit, ok = global[channel]
+	}
+	if !ok || limit.Rate <= 0 {
+		return Bucket{}, false
+	}
+
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return Bucket{Key: key, Rate: limit.Rate, Burst: burst}, true
+}
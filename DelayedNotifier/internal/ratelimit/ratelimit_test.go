@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Mock Store для тестирования
+type MockStore struct {
+	TakeTokensFunc func(ctx context.Context, buckets []Bucket, now time.Time) (time.Duration, error)
+	buckets        []Bucket
+}
+
+func (m *MockStore) TakeTokens(ctx context.Context, buckets []Bucket, now time.Time) (time.Duration, error) {
+	m.buckets = buckets
+	if m.TakeTokensFunc != nil {
+		return m.TakeTokensFunc(ctx, buckets, now)
+	}
+	return 0, nil
+}
+
+func TestBucket(t *testing.T) {
+	b := Bucket{Key: "k", Rate: 2, Burst: 3}
+	at := time.Now()
+
+	tests := []struct {
+		name         string
+		tokens       float64
+		elapsed      time.Duration
+		expectTokens float64
+		expectWait   time.Duration
+	}{
+		{name: "Refill is capped by burst", tokens: 2, elapsed: time.Hour, expectTokens: 3},
+		{name: "Partial refill", tokens: 0, elapsed: 250 * time.Millisecond, expectTokens: 0.5, expectWait: 250 * time.Millisecond},
+		{name: "Empty bucket", tokens: -1, expectTokens: -1, expectWait: time.Second},
+		{name: "Clock moved back", tokens: 1, elapsed: -time.Second, expectTokens: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := b.Available(tt.tokens, at, at.Add(tt.elapsed))
+			if tokens != tt.expectTokens {
+				t.Errorf("Expected %v tokens, got %v", tt.expectTokens, tokens)
+			}
+			if wait := b.Wait(tokens); wait != tt.expectWait {
+				t.Errorf("Expected wait %s, got %s", tt.expectWait, wait)
+			}
+		})
+	}
+}
+
+func TestLimiter_Buckets(t *testing.T) {
+	cfg := config.RateLimit{
+		RateLimits: config.RateLimits{
+			Channel:   map[string]config.Limit{models.ChannelEmail: {Rate: 10, Burst: 20}},
+			Recipient: map[string]config.Limit{models.ChannelEmail: {Rate: 0.5}, models.ChannelLog: {Rate: 0}},
+		},
+		Tenants: map[string]config.RateLimits{
+			"acme": {Channel: map[string]config.Limit{models.ChannelEmail: {Rate: 100, Burst: 100}}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		channel  string
+		tenant   string
+		expected string
+	}{
+		{
+			name:     "Channel and recipient limits",
+			channel:  models.ChannelEmail,
+			expected: "[{channel:email 10 20} {recipient:email:a@example.com 0.5 1}]",
+		},
+		{
+			name:     "Tenant overrides channel limit only",
+			channel:  models.ChannelEmail,
+			tenant:   "acme",
+			expected: "[{tenant:acme:channel:email 100 100} {recipient:email:a@example.com 0.5 1}]",
+		},
+		{
+			name:     "Unknown tenant uses global limits",
+			channel:  models.ChannelEmail,
+			tenant:   "other",
+			expected: "[{channel:email 10 20} {recipient:email:a@example.com 0.5 1}]",
+		},
+		{
+			name:     "Zero rate is unlimited",
+			expected: "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notification := models.Notification{}
+			notification.Channel = tt.channel
+			notification.Recipient = "a@example.com"
+			notification.Tenant = tt.tenant
+
+			if got := fmt.Sprint(New(&MockStore{}, cfg).buckets(notification)); got != tt.expected {
+				t.Errorf("Expected buckets %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLimiter_Reserve(t *testing.T) {
+	cfg := config.RateLimit{RateLimits: config.RateLimits{
+		Channel: map[string]config.Limit{models.ChannelLog: {Rate: 1}},
+	}}
+	store := &MockStore{
+		TakeTokensFunc: func(ctx context.Context, buckets []Bucket, now time.Time) (time.Duration, error) {
+			return time.Second, nil
+		},
+	}
+
+	// задержка – время до токена плюс случайная добавка не больше него
+	wait, err := New(store, cfg).Reserve(context.Background(), models.Notification{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if wait < time.Second || wait > 2*time.Second {
+		t.Errorf("Expected wait within [1s, 2s], got %s", wait)
+	}
+
+	// без лимитов хранилище не опрашивается
+	store.buckets = nil
+	cfg.Channel = nil
+	if wait, _ := New(store, cfg).Reserve(context.Background(), models.Notification{}); wait != 0 || store.buckets != nil {
+		t.Errorf("Expected no limit, got wait %s and buckets %v", wait, store.buckets)
+	}
+}
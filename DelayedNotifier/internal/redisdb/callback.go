@@ -0,0 +1,97 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ZSET событий для callback_url уведомлений (models.CallbackEvent в JSON),
+// score – время следующей попытки отправки (мс)
+const callbackKey = "notify:callbacks"
+
+// callbackLua подключается к скриптам, меняющим статус: enqueueCallback ставит событие event
+// в очередь отправки со score at, если у уведомления key задан callback_url.
+// Пустое event – о новом статусе callback не отправляется.
+const callbackLua = `
+local function enqueueCallback(key, event, at)
+	if event ~= "" and (redis.call("HGET", key, "callback_url") or "") ~= "" then
+		redis.call("ZADD", "` + callbackKey + `", at, event)
+	end
+end
+`
+
+// callbackEvent аргументы enqueueCallback для перехода уведомления в status сейчас:
+// событие в JSON и время в мс; для статуса не из models.CallbackStatuses событие пустое
+func callbackEvent(notificationUUID string, status string) (string, int64) {
+	now := time.Now()
+	if !slices.Contains(models.CallbackStatuses, status) {
+		return "", now.UnixMilli()
+	}
+	data, _ := json.Marshal(models.CallbackEvent{ID: uuid.NewString(), UUID: notificationUUID, Status: status, At: now})
+	return string(data), now.UnixMilli()
+}
+
+// DueCallbacks возвращает до limit событий, время отправки которых наступило к now
+func (rc *RedisConnection) DueCallbacks(ctx context.Context, now time.Time, limit int) ([]models.CallbackEntry, error) {
+	members, err := rc.rdb.ZRangeByScoreWithScores(ctx, callbackKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get callbacks from Redis DB")
+	}
+
+	entries := make([]models.CallbackEntry, 0, len(members))
+	for _, member := range members {
+		key, _ := member.Member.(string)
+		var event models.CallbackEvent
+		if err := json.Unmarshal([]byte(key), &event); err != nil {
+			// повреждённое событие отправить нельзя
+			rc.rdb.ZRem(ctx, callbackKey, key)
+			continue
+		}
+		entries = append(entries, models.CallbackEntry{Event: event, Key: key, DueAt: time.UnixMilli(int64(member.Score))})
+	}
+	return entries, nil
+}
+
+// AckCallback удаляет отправленное (или отброшенное) событие из очереди
+func (rc *RedisConnection) AckCallback(ctx context.Context, entry models.CallbackEntry) error {
+	if err := rc.rdb.ZRem(ctx, callbackKey, entry.Key).Err(); err != nil {
+		return errors.New("Failed to ack callback in Redis DB")
+	}
+	return nil
+}
+
+// retryCallbackScript заменяет событие ARGV[1] на ARGV[2] со score ARGV[3],
+// если событие ещё в очереди (его не отправил другой экземпляр сервиса)
+var retryCallbackScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[1], ARGV[3], ARGV[2])
+return 1
+`)
+
+// RetryCallback откладывает повторную отправку события до until, увеличивая его счётчик попыток
+func (rc *RedisConnection) RetryCallback(ctx context.Context, entry models.CallbackEntry, until time.Time) error {
+	event := entry.Event
+	event.Attempts++
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := retryCallbackScript.Run(ctx, rc.rdb, []string{callbackKey}, entry.Key, data, until.UnixMilli()).Err(); err != nil {
+		return errors.New("Failed to retry callback in Redis DB")
+	}
+	return nil
+}
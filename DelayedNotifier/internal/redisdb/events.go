@@ -0,0 +1,111 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	// поток событий смены статуса (поле event – JSON) для возобновления GET /notify/events
+	eventsStreamKey = "notify:events"
+	// канал Pub/Sub с теми же событиями: "<id потока> <JSON>"
+	eventsChannel = "notify:events"
+	// примерная длина потока: более старые события для возобновления недоступны
+	eventsStreamLength = "100000"
+)
+
+// eventsLua подключается к скриптам, меняющим статус: publishEvent добавляет смену статуса
+// change (models.StatusChange в JSON) уведомления key в поток событий и публикует её в канал
+const eventsLua = `
+local function publishEvent(key, change)
+	local event = cjson.decode(change)
+	local fields = redis.call("HMGET", key, "parent_uuid", "tags", "tenant")
+	event["uuid"] = key
+	event["parent_uuid"] = fields[1] or ""
+	event["tags"] = fields[2] or ""
+	event["tenant"] = fields[3] or ""
+	local payload = cjson.encode(event)
+	local id = redis.call("XADD", "` + eventsStreamKey + `", "MAXLEN", "~", ` + eventsStreamLength + `, "*", "event", payload)
+	redis.call("PUBLISH", "` + eventsChannel + `", id .. " " .. payload)
+end
+`
+
+// streamEvent событие в том виде, в каком его записывает publishEvent
+type streamEvent struct {
+	models.StatusChange
+	UUID       string `json:"uuid"`
+	ParentUUID string `json:"parent_uuid"`
+	Tags       string `json:"tags"`
+	Tenant     string `json:"tenant"`
+}
+
+func decodeEvent(id string, payload string) (models.StatusEvent, error) {
+	var event streamEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return models.StatusEvent{}, err
+	}
+	return models.StatusEvent{
+		ID:           id,
+		UUID:         event.UUID,
+		ParentUUID:   event.ParentUUID,
+		StatusChange: event.StatusChange,
+		Tags:         splitTags(event.Tags),
+		Tenant:       event.Tenant,
+	}, nil
+}
+
+// SubscribeEvents подписывается на события смены статуса через Pub/Sub;
+// канал закрывается при отмене ctx или обрыве подписки
+func (rc *RedisConnection) SubscribeEvents(ctx context.Context) (<-chan models.StatusEvent, error) {
+	pubsub := rc.rdb.Subscribe(ctx, eventsChannel)
+	// подписка подтверждена – события не теряются между подпиской и чтением
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, errors.New("Failed to subscribe to Redis DB events")
+	}
+
+	events := make(chan models.StatusEvent)
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+		for {
+			msg, err := pubsub.ReceiveMessage(ctx)
+			if err != nil {
+				return
+			}
+			id, payload, _ := strings.Cut(msg.Payload, " ")
+			event, err := decodeEvent(id, payload)
+			if err != nil {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// EventsAfter возвращает до limit событий из потока, появившихся после события id
+func (rc *RedisConnection) EventsAfter(ctx context.Context, id string, limit int) ([]models.StatusEvent, error) {
+	messages, err := rc.rdb.XRangeN(ctx, eventsStreamKey, "("+id, "+", int64(limit)).Result()
+	if err != nil {
+		return nil, errors.New("Failed to read events from Redis DB")
+	}
+
+	events := make([]models.StatusEvent, 0, len(messages))
+	for _, message := range messages {
+		payload, _ := message.Values["event"].(string)
+		event, err := decodeEvent(message.ID, payload)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
@@ -0,0 +1,97 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// рассылка (models.Notification с Recipients) в JSON; доставки получателям – обычные уведомления
+const fanoutKeyPrefix = "notify:fanout:"
+
+// createFanoutScript сохраняет рассылку KEYS[1] и уведомления получателям одной операцией.
+// ARGV[1] – рассылка в JSON, ARGV[2] – её UUID, ARGV[3] – первая запись истории статусов,
+// ARGV[4] – score outbox, далее для каждого получателя JSON массив [uuid, поле, значение, ...].
+// Если занят хоть один UUID, ничего не записывается.
+var createFanoutScript = redis.NewScript(reindexLua + eventsLua + `
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", ARGV[2]) == 1 then
+	return 0
+end
+local children = {}
+for i = 5, #ARGV do
+	local fields = cjson.decode(ARGV[i])
+	if redis.call("EXISTS", fields[1]) == 1 or redis.call("EXISTS", "` + fanoutKeyPrefix + `" .. fields[1]) == 1 then
+		return 0
+	end
+	children[#children + 1] = fields
+end
+
+redis.call("SET", KEYS[1], ARGV[1])
+for _, fields in ipairs(children) do
+	local key = table.remove(fields, 1)
+	local history = "` + historyKeyPrefix + `" .. key
+	redis.call("HSET", key, unpack(fields))
+	redis.call("DEL", history)
+	redis.call("RPUSH", history, ARGV[3])
+	redis.call("ZADD", KEYS[2], ARGV[4], key)
+	reindex(key, "", redis.call("HGET", key, "status"))
+	publishEvent(key, ARGV[3])
+end
+return 1
+`)
+
+// SaveFanout сохраняет рассылку и уведомления её получателям, которые сразу ставятся в outbox.
+// Сама рассылка в индексы и outbox не попадает: её статус сводится из статусов получателей.
+// Если UUID рассылки или получателя занят, возвращается models.ErrAlreadyExists
+func (rc *RedisConnection) SaveFanout(ctx context.Context, parent models.Notification, children []models.Notification) error {
+	// как и в хеше уведомления, исходное send_at не хранится: время отправки уже в fire_at
+	parent.SendAt, parent.AllowPast = "", false
+	data, err := json.Marshal(parent)
+	if err != nil {
+		return err
+	}
+
+	args := []any{data, parent.UUID, statusChange(parent.Status), time.Now().UnixMilli()}
+	for _, child := range children {
+		fields := []string{child.UUID}
+		for _, value := range messageFields(child) {
+			fields = append(fields, fmt.Sprint(value))
+		}
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		args = append(args, encoded)
+	}
+
+	created, err := createFanoutScript.Run(ctx, rc.rdb, []string{fanoutKeyPrefix + parent.UUID, outboxKey}, args...).Int()
+	if err != nil {
+		return errors.New("Failed to save fan-out into Redis DB")
+	}
+	if created == 0 {
+		return models.ErrAlreadyExists
+	}
+	return nil
+}
+
+// GetFanout загружает рассылку по UUID; если её нет, возвращает models.ErrNotFound
+func (rc *RedisConnection) GetFanout(ctx context.Context, uuid string) (models.Notification, error) {
+	data, err := rc.rdb.Get(ctx, fanoutKeyPrefix+uuid).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Notification{}, models.ErrNotFound
+	}
+	if err != nil {
+		return models.Notification{}, errors.New("Failed to get fan-out from Redis DB")
+	}
+
+	var parent models.Notification
+	if err := json.Unmarshal(data, &parent); err != nil {
+		return models.Notification{}, errors.New("Failed to decode fan-out from Redis DB")
+	}
+	return parent, nil
+}
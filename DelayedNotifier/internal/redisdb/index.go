@@ -0,0 +1,204 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ZSET всех уведомлений, score – fire_at (мс)
+	allIndexKey = "notify:index:all"
+	// ZSET уведомлений по статусу, score – fire_at (мс)
+	statusIndexPrefix = "notify:index:status:"
+	// сколько элементов индекса читается за один запрос при фильтрации
+	indexScanBatch = 200
+)
+
+// reindexLua подключается к скриптам, меняющим статус или fire_at: reindex переносит
+// уведомление key из индекса статуса old в индекс new и обновляет score по fire_at.
+// Ключи индексов вычисляются внутри скрипта, поэтому рассчитано на один экземпляр Redis.
+const reindexLua = `
+local function reindex(key, old, new)
+	local fireAt = tonumber(redis.call("HGET", key, "fire_at") or "0") or 0
+	if old ~= "" and old ~= new then
+		redis.call("ZREM", "` + statusIndexPrefix + `" .. old, key)
+	end
+	redis.call("ZADD", "` + statusIndexPrefix + `" .. new, fireAt, key)
+	redis.call("ZADD", "` + allIndexKey + `", fireAt, key)
+end
+`
+
+// ListMessages возвращает уведомления по фильтру, отсортированные по fire_at (при равенстве – по UUID),
+// и курсор следующей страницы (пустой – страница последняя). Статус и окно времени выбираются
+// по индексу, получатель и метка проверяются по самой записи.
+func (rc *RedisConnection) ListMessages(ctx context.Context, filter models.ListFilter) ([]models.Notification, string, error) {
+	key := allIndexKey
+	if filter.Status != "" {
+		key = statusIndexPrefix + filter.Status
+	}
+
+	min, max := "-inf", "+inf"
+	if !filter.From.IsZero() {
+		min = strconv.FormatInt(filter.From.UnixMilli(), 10)
+	}
+	if !filter.To.IsZero() {
+		max = strconv.FormatInt(filter.To.UnixMilli(), 10)
+	}
+
+	var after *indexCursor
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &cursor
+		// keyset пагинация: продолжаем с score последнего элемента страницы
+		if filter.From.IsZero() || cursor.score > filter.From.UnixMilli() {
+			min = strconv.FormatInt(cursor.score, 10)
+		}
+	}
+
+	items := make([]models.Notification, 0, filter.Limit)
+	var last indexCursor
+	for offset := int64(0); ; offset += indexScanBatch {
+		members, err := rc.rdb.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min:    min,
+			Max:    max,
+			Offset: offset,
+			Count:  indexScanBatch,
+		}).Result()
+		if err != nil {
+			return nil, "", errors.New("Failed to list messages from Redis DB")
+		}
+
+		for _, member := range members {
+			position := indexCursor{score: int64(member.Score)}
+			position.uuid, _ = member.Member.(string)
+			if after != nil && !position.after(*after) {
+				continue
+			}
+
+			notification, err := rc.GetMessage(ctx, position.uuid)
+			if errors.Is(err, models.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			if !matches(notification, filter) {
+				continue
+			}
+
+			// элемент сверх limit означает, что есть следующая страница
+			if len(items) == filter.Limit {
+				return items, last.encode(), nil
+			}
+			items = append(items, notification)
+			last = position
+		}
+
+		if len(members) < indexScanBatch {
+			return items, "", nil
+		}
+	}
+}
+
+func matches(notification models.Notification, filter models.ListFilter) bool {
+	if filter.Status != "" && notification.Status != filter.Status {
+		return false
+	}
+	if filter.Recipient != "" && notification.Recipient != filter.Recipient {
+		return false
+	}
+	if filter.Tag != "" && !slices.Contains(notification.Tags, filter.Tag) {
+		return false
+	}
+	return true
+}
+
+// indexCursor позиция в индексе: score (fire_at, мс) и UUID последнего элемента страницы
+type indexCursor struct {
+	score int64
+	uuid  string
+}
+
+// after сравнивает позиции в порядке ZSET: по score, затем лексикографически по UUID
+func (c indexCursor) after(other indexCursor) bool {
+	if c.score != other.score {
+		return c.score > other.score
+	}
+	return c.uuid > other.uuid
+}
+
+func (c indexCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.score, 10) + ":" + c.uuid))
+}
+
+func decodeCursor(value string) (indexCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return indexCursor{}, models.ErrInvalidCursor
+	}
+	score, uuid, ok := strings.Cut(string(data), ":")
+	if !ok || uuid == "" {
+		return indexCursor{}, models.ErrInvalidCursor
+	}
+	parsed, err := strconv.ParseInt(score, 10, 64)
+	if err != nil {
+		return indexCursor{}, models.ErrInvalidCursor
+	}
+	return indexCursor{score: parsed, uuid: uuid}, nil
+}
+
+// Backlog считает наступившие к now, но ещё не доставленные уведомления (pending и retrying)
+// по приоритету. Индексы статусов читаются пачками, приоритеты – pipeline-ом на пачку.
+func (rc *RedisConnection) Backlog(ctx context.Context, now time.Time) (map[string]int, error) {
+	backlog := make(map[string]int, len(models.Priorities))
+	for _, priority := range models.Priorities {
+		backlog[priority] = 0
+	}
+
+	max := strconv.FormatInt(now.UnixMilli(), 10)
+	for _, status := range []string{models.StatusPending, models.StatusRetrying} {
+		for offset := int64(0); ; offset += indexScanBatch {
+			uuids, err := rc.rdb.ZRangeByScore(ctx, statusIndexPrefix+status, &redis.ZRangeBy{
+				Min:    "-inf",
+				Max:    max,
+				Offset: offset,
+				Count:  indexScanBatch,
+			}).Result()
+			if err != nil {
+				return nil, errors.New("Failed to count backlog in Redis DB")
+			}
+
+			pipe := rc.rdb.Pipeline()
+			priorities := make([]*redis.StringCmd, len(uuids))
+			for i, uuid := range uuids {
+				priorities[i] = pipe.HGet(ctx, uuid, "priority")
+			}
+			if len(uuids) > 0 {
+				// у уведомлений, созданных до появления приоритетов, поля нет (redis.Nil)
+				_, _ = pipe.Exec(ctx)
+			}
+			for _, cmd := range priorities {
+				if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+					return nil, errors.New("Failed to count backlog in Redis DB")
+				}
+				backlog[models.Priorities[models.PriorityLevel(cmd.Val())]]++
+			}
+
+			if len(uuids) < indexScanBatch {
+				break
+			}
+		}
+	}
+	return backlog, nil
+}
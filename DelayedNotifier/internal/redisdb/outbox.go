@@ -0,0 +1,133 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ZSET уведомлений, ожидающих публикации в очередь, score – время, с которого можно публиковать (мс)
+const outboxKey = "notify:outbox"
+
+// outboxLua подключается к скриптам, меняющим расписание уведомления: requeue ставит уведомление
+// key в outbox со score at (мс) и запоминает время постановки. В отличие от EnqueueOutbox score
+// перезаписывается: relay, публикующий прежнюю версию, не снимет новую запись (outboxCASScript).
+const outboxLua = `
+local function requeue(key, at)
+	redis.call("HSET", key, "enqueued_at", at)
+	redis.call("ZADD", "` + outboxKey + `", at, key)
+end
+`
+
+// DueOutbox возвращает до limit записей outbox, время публикации которых наступило к now
+func (rc *RedisConnection) DueOutbox(ctx context.Context, now time.Time, limit int) ([]models.OutboxEntry, error) {
+	members, err := rc.rdb.ZRangeByScoreWithScores(ctx, outboxKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get outbox from Redis DB")
+	}
+
+	entries := make([]models.OutboxEntry, 0, len(members))
+	for _, member := range members {
+		uuid, _ := member.Member.(string)
+		entries = append(entries, models.OutboxEntry{UUID: uuid, DueAt: time.UnixMilli(int64(member.Score))})
+	}
+	return entries, nil
+}
+
+// outboxCASScript меняет запись outbox, только если её score не изменился с момента чтения:
+// уведомление, заново поставленное в outbox во время публикации, не теряется.
+// ARGV[2] – новый score, пустой – удалить запись.
+var outboxCASScript = redis.NewScript(`
+local score = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+if ARGV[3] == "" then
+	redis.call("ZREM", KEYS[1], ARGV[1])
+else
+	redis.call("ZADD", KEYS[1], ARGV[3], ARGV[1])
+end
+return 1
+`)
+
+// AckOutbox удаляет опубликованную запись outbox
+func (rc *RedisConnection) AckOutbox(ctx context.Context, entry models.OutboxEntry) error {
+	err := outboxCASScript.Run(ctx, rc.rdb, []string{outboxKey}, entry.UUID, entry.DueAt.UnixMilli(), "").Err()
+	if err != nil {
+		return errors.New("Failed to ack outbox entry in Redis DB")
+	}
+	return nil
+}
+
+// DelayOutbox откладывает повторную публикацию записи outbox до until
+func (rc *RedisConnection) DelayOutbox(ctx context.Context, entry models.OutboxEntry, until time.Time) error {
+	err := outboxCASScript.Run(ctx, rc.rdb, []string{outboxKey}, entry.UUID, entry.DueAt.UnixMilli(), until.UnixMilli()).Err()
+	if err != nil {
+		return errors.New("Failed to delay outbox entry in Redis DB")
+	}
+	return nil
+}
+
+// enqueueOutboxScript запоминает время постановки ARGV[1] в поле enqueued_at уведомления KEYS[1]
+// и ставит его в outbox KEYS[2], если его там ещё нет; удалённое уведомление не ставится
+var enqueueOutboxScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "enqueued_at", ARGV[1])
+redis.call("ZADD", KEYS[2], "NX", ARGV[1], KEYS[1])
+return 1
+`)
+
+// EnqueueOutbox ставит уведомление в outbox, если его там ещё нет, и запоминает время постановки
+func (rc *RedisConnection) EnqueueOutbox(ctx context.Context, uuid string, at time.Time) error {
+	err := enqueueOutboxScript.Run(ctx, rc.rdb, []string{uuid, outboxKey}, at.UnixMilli()).Err()
+	if err != nil {
+		return errors.New("Failed to enqueue outbox entry into Redis DB")
+	}
+	return nil
+}
+
+// staleMessagesScript выбирает из индекса статуса KEYS[1] по возрастанию fire_at до ARGV[2]
+// уведомлений со временем срабатывания и последней постановки в outbox (enqueued_at) раньше ARGV[1].
+// Пропускаются только недавно поставленные в outbox, поэтому просмотр ограничен их числом.
+var staleMessagesScript = redis.NewScript(`
+local before = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local stale = {}
+local offset = 0
+while #stale < limit do
+	local uuids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", "(" .. ARGV[1], "LIMIT", offset, limit)
+	if #uuids == 0 then
+		break
+	end
+	for _, uuid in ipairs(uuids) do
+		if tonumber(redis.call("HGET", uuid, "enqueued_at") or "0") < before then
+			stale[#stale + 1] = uuid
+			if #stale == limit then
+				break
+			end
+		end
+	end
+	offset = offset + #uuids
+end
+return stale
+`)
+
+// StaleMessages возвращает до limit самых старых уведомлений в статусе status, чьё время
+// срабатывания и последняя постановка в outbox (EnqueueOutbox) раньше before
+func (rc *RedisConnection) StaleMessages(ctx context.Context, status string, before time.Time, limit int) ([]string, error) {
+	uuids, err := staleMessagesScript.Run(ctx, rc.rdb, []string{statusIndexPrefix + status}, before.UnixMilli(), limit).StringSlice()
+	if err != nil {
+		return nil, errors.New("Failed to get stale messages from Redis DB")
+	}
+	return uuids, nil
+}
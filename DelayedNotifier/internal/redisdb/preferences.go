@@ -0,0 +1,58 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// models.Preferences в JSON по каналу и адресу получателя
+const preferencesKeyPrefix = "notify:preferences:"
+
+func preferencesKey(channel string, address string) string {
+	return preferencesKeyPrefix + channel + ":" + address
+}
+
+// SavePreferences сохраняет настройки получателя, заменяя прежние
+func (rc *RedisConnection) SavePreferences(ctx context.Context, prefs models.Preferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	if err := rc.rdb.Set(ctx, preferencesKey(prefs.Channel, prefs.Address), data, 0).Err(); err != nil {
+		return errors.New("Failed to save preferences into Redis DB")
+	}
+	return nil
+}
+
+// GetPreferences загружает настройки получателя; models.ErrNotFound – если их нет
+func (rc *RedisConnection) GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error) {
+	data, err := rc.rdb.Get(ctx, preferencesKey(channel, address)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.Preferences{}, models.ErrNotFound
+	}
+	if err != nil {
+		return models.Preferences{}, errors.New("Failed to get preferences from Redis DB")
+	}
+
+	var prefs models.Preferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return models.Preferences{}, errors.New("Failed to decode preferences from Redis DB")
+	}
+	return prefs, nil
+}
+
+// DeletePreferences удаляет настройки получателя; models.ErrNotFound – если их нет
+func (rc *RedisConnection) DeletePreferences(ctx context.Context, channel string, address string) error {
+	deleted, err := rc.rdb.Del(ctx, preferencesKey(channel, address)).Result()
+	if err != nil {
+		return errors.New("Failed to delete preferences from Redis DB")
+	}
+	if deleted == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/ratelimit"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// хеш token bucket {tokens, ts}: число токенов на момент ts (мс)
+const rateLimitKeyPrefix = "notify:ratelimit:"
+
+// takeTokensScript – ratelimit.Bucket.Available и Wait для всех корзин KEYS сразу.
+// ARGV[1] – текущее время (мс), далее для каждой корзины rate (токенов в секунду) и burst.
+// Возвращает 0, забрав по токену из каждой корзины, или время ожидания (мс), ничего не забирая.
+var takeTokensScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local wait = 0
+local tokens = {}
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	local state = redis.call("HMGET", key, "tokens", "ts")
+	local available = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	if now > ts then
+		available = available + (now - ts) * rate / 1000
+	end
+	available = math.min(available, burst)
+	if available < 1 then
+		wait = math.max(wait, math.ceil((1 - available) * 1000 / rate))
+	end
+	tokens[i] = available
+end
+if wait > 0 then
+	return wait
+end
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[i * 2])
+	local burst = tonumber(ARGV[i * 2 + 1])
+	redis.call("HSET", key, "tokens", tostring(tokens[i] - 1), "ts", now)
+	-- полная корзина не отличается от отсутствующей, храним её только до заполнения
+	redis.call("PEXPIRE", key, math.ceil(burst * 1000 / rate) + 1000)
+end
+return 0
+`)
+
+// TakeTokens атомарно забирает по токену из всех корзин или возвращает время ожидания токенов
+func (rc *RedisConnection) TakeTokens(ctx context.Context, buckets []ratelimit.Bucket, now time.Time) (time.Duration, error) {
+	keys := make([]string, len(buckets))
+	args := []any{now.UnixMilli()}
+	for i, bucket := range buckets {
+		keys[i] = rateLimitKeyPrefix + bucket.Key
+		args = append(args, bucket.Rate, bucket.Burst)
+	}
+
+	wait, err := takeTokensScript.Run(ctx, rc.rdb, keys, args...).Int64()
+	if err != nil {
+		return 0, errors.New("Failed to take rate limit tokens from Redis DB")
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
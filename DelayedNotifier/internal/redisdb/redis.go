@@ -3,11 +3,27 @@ package redisdb
 import (
 	"DelayedNotifier/internal/models"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// ZSET UUID окончательно неудачных уведомлений, score – время попадания в DLQ (мс)
+	deadLettersKey = "notify:dead"
+	// список попыток доставки уведомления в JSON
+	attemptsKeyPrefix = "notify:attempts:"
+	// список смен статуса уведомления (models.StatusChange в JSON)
+	historyKeyPrefix = "notify:history:"
+	// models.IdempotencyRecord в JSON по значению заголовка Idempotency-Key
+	idempotencyKeyPrefix = "notify:idempotency:"
+)
+
 type RedisConnection struct {
 	rdb *redis.Client
 }
@@ -25,17 +41,233 @@ func DeclareRedisDataBase(options redis.Options) *RedisConnection {
 	return &RedisConnection{rdb: rdb}
 }
 
+// createMessageScript создаёт хеш уведомления, только если ключ ещё не занят ни уведомлением,
+// ни рассылкой (KEYS[4]), начинает историю статусов записью ARGV[1] и ставит уведомление
+// в outbox со score ARGV[2]
+var createMessageScript = redis.NewScript(reindexLua + eventsLua + `
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", KEYS[4]) == 1 then
+	return 0
+end
+redis.call("HSET", KEYS[1], unpack(ARGV, 3))
+redis.call("DEL", KEYS[2])
+redis.call("RPUSH", KEYS[2], ARGV[1])
+redis.call("ZADD", KEYS[3], ARGV[2], KEYS[1])
+reindex(KEYS[1], "", redis.call("HGET", KEYS[1], "status"))
+publishEvent(KEYS[1], ARGV[1])
+return 1
+`)
+
+// SaveMessage сохраняет новое уведомление и в той же операции ставит его в outbox:
+// в очередь его публикует outbox relay. Существующее уведомление не перезаписывается
+// и возвращается models.ErrAlreadyExists
 func (rc *RedisConnection) SaveMessage(ctx context.Context, notif models.Notification) error {
-	n, err := rc.rdb.HSet(ctx, notif.UUID, notif).Result()
+	keys := []string{notif.UUID, historyKeyPrefix + notif.UUID, outboxKey, fanoutKeyPrefix + notif.UUID}
+	args := append([]any{statusChange(notif.Status), time.Now().UnixMilli()}, messageFields(notif)...)
+	created, err := createMessageScript.Run(ctx, rc.rdb, keys, args...).Int()
 	if err != nil {
 		return errors.New("Failed to save message into Redis DB")
-	} else if n == 0 {
-		return errors.New("got message is empty")
+	}
+	if created == 0 {
+		return models.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+// SaveMessages сохраняет пачку новых уведомлений одним pipeline запросом к Redis: каждое
+// создаётся и ставится в outbox как в SaveMessage, но независимо от остальных. Возвращает ошибку
+// для каждого уведомления (models.ErrAlreadyExists – UUID занят, в том числе уведомлением
+// раньше в пачке) либо общую ошибку, если скрипт не удалось загрузить
+func (rc *RedisConnection) SaveMessages(ctx context.Context, notifs []models.Notification) ([]error, error) {
+	// в pipeline нельзя повторить EVALSHA после NOSCRIPT, поэтому скрипт загружается заранее
+	if err := createMessageScript.Load(ctx, rc.rdb).Err(); err != nil {
+		return nil, errors.New("Failed to save messages into Redis DB")
 	}
 
+	now := time.Now().UnixMilli()
+	cmds := make([]*redis.Cmd, len(notifs))
+	// ошибка pipeline дублирует ошибку первой неудачной команды, результаты разбираются по командам
+	rc.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, notif := range notifs {
+			keys := []string{notif.UUID, historyKeyPrefix + notif.UUID, outboxKey, fanoutKeyPrefix + notif.UUID}
+			args := append([]any{statusChange(notif.Status), now}, messageFields(notif)...)
+			cmds[i] = createMessageScript.EvalSha(ctx, pipe, keys, args...)
+		}
+		return nil
+	})
+
+	results := make([]error, len(notifs))
+	for i, cmd := range cmds {
+		created, err := cmd.Int()
+		if err != nil {
+			results[i] = errors.New("Failed to save message into Redis DB")
+		} else if created == 0 {
+			results[i] = models.ErrAlreadyExists
+		}
+	}
+	return results, nil
+}
+
+// messageFields поля хеша нового уведомления парами имя, значение
+func messageFields(notif models.Notification) []any {
+	return []any{
+		"status", notif.Status,
+		"message", notif.Message,
+		"scheduled_at", notif.ScheduledAt,
+		"channel", notif.Channel,
+		"recipient", notif.Recipient,
+		"subject", notif.Subject,
+		"max_attempts", notif.MaxAttempts,
+		"attempts", notif.Attempts,
+		"fire_at", unixMilli(notif.FireAt),
+		"timezone", notif.Timezone,
+		"cron", notif.Cron,
+		"until", unixMilli(notif.Until),
+		"max_occurrences", notif.MaxOccurrences,
+		"occurrences", notif.Occurrences,
+		"tags", strings.Join(notif.Tags, ","),
+		"version", notif.Version,
+		"template", notif.Template,
+		"template_version", notif.TemplateVersion,
+		"data", encodeData(notif.Data),
+		"parent_uuid", notif.ParentUUID,
+		"tenant", notif.Tenant,
+		"priority", notif.Priority,
+		"callback_url", notif.CallbackURL,
+	}
+}
+
+// DeleteMessage удаляет уведомление (или запись рассылки) вместе с историей попыток и статусов
+func (rc *RedisConnection) DeleteMessage(ctx context.Context, uuid string) error {
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, uuid, attemptsKeyPrefix+uuid, historyKeyPrefix+uuid, fanoutKeyPrefix+uuid)
+		pipe.ZRem(ctx, allIndexKey, uuid)
+		pipe.ZRem(ctx, outboxKey, uuid)
+		for _, status := range models.Statuses {
+			pipe.ZRem(ctx, statusIndexPrefix+status, uuid)
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("Failed to delete message from Redis DB")
+	}
 	return nil
 }
 
+// GetMessage загружает уведомление по UUID; если записи нет, возвращает models.ErrNotFound
+func (rc *RedisConnection) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	fields, err := rc.rdb.HGetAll(ctx, uuid).Result()
+	if err != nil {
+		return models.Notification{}, errors.New("Failed to get message from Redis DB; err: " + err.Error())
+	}
+	if len(fields) == 0 {
+		return models.Notification{}, models.ErrNotFound
+	}
+
+	scheduledAt, _ := strconv.ParseInt(fields["scheduled_at"], 10, 64)
+	maxAttempts, _ := strconv.Atoi(fields["max_attempts"])
+	attempts, _ := strconv.Atoi(fields["attempts"])
+	occurrences, _ := strconv.Atoi(fields["occurrences"])
+	maxOccurrences, _ := strconv.Atoi(fields["max_occurrences"])
+	version, _ := strconv.Atoi(fields["version"])
+	templateVersion, _ := strconv.Atoi(fields["template_version"])
+
+	return models.Notification{
+		UUID:        uuid,
+		Status:      fields["status"],
+		LastError:   fields["last_error"],
+		Attempts:    attempts,
+		FireAt:      parseMilli(fields["fire_at"]),
+		Occurrences: occurrences,
+		Version:     version,
+		ParentUUID:  fields["parent_uuid"],
+		NotificationCard: models.NotificationCard{
+			Message:         fields["message"],
+			ScheduledAt:     scheduledAt,
+			Channel:         fields["channel"],
+			Recipient:       fields["recipient"],
+			Subject:         fields["subject"],
+			MaxAttempts:     maxAttempts,
+			Timezone:        fields["timezone"],
+			Cron:            fields["cron"],
+			Until:           parseMilli(fields["until"]),
+			MaxOccurrences:  maxOccurrences,
+			Tags:            splitTags(fields["tags"]),
+			Tenant:          fields["tenant"],
+			Priority:        fields["priority"],
+			CallbackURL:     fields["callback_url"],
+			Template:        fields["template"],
+			TemplateVersion: templateVersion,
+			Data:            decodeData(fields["data"]),
+		},
+	}, nil
+}
+
+// переменные шаблона хранятся JSON строкой, пустая строка – нет данных
+func encodeData(data map[string]any) string {
+	if len(data) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(data)
+	return string(encoded)
+}
+
+func decodeData(value string) map[string]any {
+	if value == "" {
+		return nil
+	}
+	var data map[string]any
+	_ = json.Unmarshal([]byte(value), &data)
+	return data
+}
+
+func splitTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// GetHistory возвращает смены статуса уведомления в порядке их записи
+func (rc *RedisConnection) GetHistory(ctx context.Context, uuid string) ([]models.StatusChange, error) {
+	items, err := rc.rdb.LRange(ctx, historyKeyPrefix+uuid, 0, -1).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get status history from Redis DB")
+	}
+
+	history := make([]models.StatusChange, 0, len(items))
+	for _, item := range items {
+		var change models.StatusChange
+		if err := json.Unmarshal([]byte(item), &change); err != nil {
+			continue
+		}
+		history = append(history, change)
+	}
+	return history, nil
+}
+
+// statusChange запись истории о переходе в status в текущий момент
+func statusChange(status string) string {
+	data, _ := json.Marshal(models.StatusChange{Status: status, At: time.Now()})
+	return string(data)
+}
+
+// время хранится в миллисекундах Unix, 0 – не задано
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func parseMilli(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
 func (rc *RedisConnection) GetStatus(ctx context.Context, uuid string) (string, error) {
 	isExists, err := rc.rdb.HExists(ctx, uuid, "status").Result()
 	if !isExists || err != nil {
@@ -49,18 +281,391 @@ func (rc *RedisConnection) GetStatus(ctx context.Context, uuid string) (string,
 	return status, nil
 }
 
+// setStatusScript меняет статус (и fire_at, если ARGV[3] не пуст), дописывает историю и индексы
+// и ставит событие ARGV[4] со временем ARGV[5] в очередь callback-ов
+var setStatusScript = redis.NewScript(reindexLua + callbackLua + eventsLua + `
+local old = redis.call("HGET", KEYS[1], "status") or ""
+redis.call("HSET", KEYS[1], "status", ARGV[1])
+if ARGV[3] ~= "" then
+	redis.call("HSET", KEYS[1], "fire_at", ARGV[3])
+end
+redis.call("RPUSH", KEYS[2], ARGV[2])
+reindex(KEYS[1], old, ARGV[1])
+enqueueCallback(KEYS[1], ARGV[4], ARGV[5])
+publishEvent(KEYS[1], ARGV[2])
+return 1
+`)
+
 func (rc *RedisConnection) SaveStatus(ctx context.Context, uuid string, status string) error {
-	_, err := rc.rdb.HSet(ctx, uuid, "status", status).Result()
+	event, at := callbackEvent(uuid, status)
+	err := setStatusScript.Run(ctx, rc.rdb, []string{uuid, historyKeyPrefix + uuid}, status, statusChange(status), "", event, at).Err()
 	if err != nil {
 		return errors.New("Failed to save status into Redis DB")
 	}
 	return nil
 }
 
-func (rc *RedisConnection) DeleteMessage(ctx context.Context, uuid string) error {
-	_, err := rc.rdb.HDel(ctx, uuid, "message").Result()
+// SaveAttempt сохраняет неудачную попытку доставки в историю и как последнюю ошибку
+func (rc *RedisConnection) SaveAttempt(ctx context.Context, uuid string, attempt models.Attempt) error {
+	data, err := json.Marshal(attempt)
 	if err != nil {
-		return errors.New("Failed to delete message from Redis DB")
+		return err
+	}
+
+	_, err = rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, uuid, "last_error", attempt.Error)
+		pipe.RPush(ctx, attemptsKeyPrefix+uuid, data)
+		return nil
+	})
+	if err != nil {
+		return errors.New("Failed to save attempt into Redis DB")
+	}
+	return nil
+}
+
+// GetAttempts возвращает историю неудачных попыток доставки
+func (rc *RedisConnection) GetAttempts(ctx context.Context, uuid string) ([]models.Attempt, error) {
+	items, err := rc.rdb.LRange(ctx, attemptsKeyPrefix+uuid, 0, -1).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get attempts from Redis DB")
+	}
+
+	attempts := make([]models.Attempt, 0, len(items))
+	for _, item := range items {
+		var attempt models.Attempt
+		if err := json.Unmarshal([]byte(item), &attempt); err != nil {
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, nil
+}
+
+// MarkDead добавляет уведомление в индекс dead-letter
+func (rc *RedisConnection) MarkDead(ctx context.Context, uuid string, at time.Time) error {
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, deadLettersKey, redis.Z{Score: float64(at.UnixMilli()), Member: uuid})
+		pipe.HSet(ctx, uuid, "failed_at", at.UnixMilli())
+		return nil
+	})
+	if err != nil {
+		return errors.New("Failed to save dead letter into Redis DB")
+	}
+	return nil
+}
+
+// ListDead возвращает до limit последних dead-letter уведомлений (limit <= 0 – все)
+func (rc *RedisConnection) ListDead(ctx context.Context, limit int) ([]models.DeadLetter, error) {
+	members, err := rc.rdb.ZRevRangeWithScores(ctx, deadLettersKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get dead letters from Redis DB")
+	}
+
+	dead := make([]models.DeadLetter, 0, len(members))
+	for _, member := range members {
+		uuid, _ := member.Member.(string)
+		notification, err := rc.GetMessage(ctx, uuid)
+		if errors.Is(err, models.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		history, err := rc.GetAttempts(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+
+		dead = append(dead, models.DeadLetter{
+			Notification: notification,
+			FailedAt:     time.UnixMilli(int64(member.Score)),
+			History:      history,
+		})
+	}
+	return dead, nil
+}
+
+// reviveDeadScript атомарно убирает уведомление из dead-letter индекса, возвращает его
+// в ожидание с обнулённым счётчиком попыток и ставит в outbox на время ARGV[4] (мс)
+var reviveDeadScript = redis.NewScript(reindexLua + eventsLua + outboxLua + `
+if redis.call("ZREM", KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+local old = redis.call("HGET", ARGV[1], "status") or ""
+redis.call("HSET", ARGV[1], "status", ARGV[2], "attempts", 0)
+redis.call("HDEL", ARGV[1], "failed_at")
+redis.call("RPUSH", KEYS[2], ARGV[3])
+reindex(ARGV[1], old, ARGV[2])
+requeue(ARGV[1], ARGV[4])
+publishEvent(ARGV[1], ARGV[3])
+return 1
+`)
+
+// ReviveDead готовит dead-letter уведомление к повторной отправке и в той же операции ставит
+// его в outbox; если его нет в индексе, возвращает models.ErrNotFound
+func (rc *RedisConnection) ReviveDead(ctx context.Context, uuid string) (models.Notification, error) {
+	revived, err := reviveDeadScript.Run(ctx, rc.rdb, []string{deadLettersKey, historyKeyPrefix + uuid},
+		uuid, models.StatusPending, statusChange(models.StatusPending), time.Now().UnixMilli()).Int()
+	if err != nil {
+		return models.Notification{}, errors.New("Failed to revive dead letter in Redis DB")
+	}
+	if revived == 0 {
+		return models.Notification{}, models.ErrNotFound
+	}
+
+	return rc.GetMessage(ctx, uuid)
+}
+
+// IncrAttempts увеличивает счётчик попыток доставки и возвращает новое значение
+func (rc *RedisConnection) IncrAttempts(ctx context.Context, uuid string) (int, error) {
+	attempts, err := rc.rdb.HIncrBy(ctx, uuid, "attempts", 1).Result()
+	if err != nil {
+		return 0, errors.New("Failed to increment attempts in Redis DB")
+	}
+	return int(attempts), nil
+}
+
+// transitionStatusScript атомарно меняет статус на ARGV[1], если текущий статус входит в ARGV[5:],
+// дописывает ARGV[2] в историю статусов KEYS[2] и ставит событие ARGV[3] со временем ARGV[4]
+// в очередь callback-ов.
+// Возвращает {0, ""} если записи нет, {1, старый статус} при успехе и {2, текущий статус} при отказе.
+var transitionStatusScript = redis.NewScript(reindexLua + callbackLua + eventsLua + `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {0, ""}
+end
+local status = redis.call("HGET", KEYS[1], "status") or ""
+for i = 5, #ARGV do
+	if status == ARGV[i] then
+		redis.call("HSET", KEYS[1], "status", ARGV[1])
+		redis.call("RPUSH", KEYS[2], ARGV[2])
+		reindex(KEYS[1], status, ARGV[1])
+		enqueueCallback(KEYS[1], ARGV[3], ARGV[4])
+		publishEvent(KEYS[1], ARGV[2])
+		return {1, status}
+	end
+end
+return {2, status}
+`)
+
+// TransitionStatus переводит уведомление в статус to, только если текущий статус один из from.
+// Возвращает предыдущий статус; models.ErrNotFound – если записи нет,
+// models.ErrStatusConflict – если текущий статус не входит в from.
+func (rc *RedisConnection) TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+	event, at := callbackEvent(uuid, to)
+	args := make([]interface{}, 0, len(from)+4)
+	args = append(args, to, statusChange(to), event, at)
+	for _, status := range from {
+		args = append(args, status)
+	}
+
+	res, err := transitionStatusScript.Run(ctx, rc.rdb, []string{uuid, historyKeyPrefix + uuid}, args...).Slice()
+	if err != nil || len(res) != 2 {
+		return "", errors.New("Failed to change status in Redis DB")
+	}
+
+	code, _ := res[0].(int64)
+	status, _ := res[1].(string)
+	switch code {
+	case 0:
+		return "", models.ErrNotFound
+	case 1:
+		return status, nil
+	default:
+		return status, fmt.Errorf("%w: status is %q", models.ErrStatusConflict, status)
+	}
+}
+
+// CancelMessage отменяет ожидающее уведомление или приостановленную серию; уже отправленные,
+// отправляемые и завершённые уведомления отменить нельзя (models.ErrStatusConflict)
+func (rc *RedisConnection) CancelMessage(ctx context.Context, uuid string) error {
+	_, err := rc.TransitionStatus(ctx, uuid, models.StatusCancelled, models.StatusPending, models.StatusRetrying, models.StatusPaused)
+	return err
+}
+
+// Reschedule сохраняет статус и новое время срабатывания уведомления
+func (rc *RedisConnection) Reschedule(ctx context.Context, uuid string, status string, fireAt time.Time) error {
+	event, at := callbackEvent(uuid, status)
+	err := setStatusScript.Run(ctx, rc.rdb, []string{uuid, historyKeyPrefix + uuid}, status, statusChange(status), unixMilli(fireAt), event, at).Err()
+	if err != nil {
+		return errors.New("Failed to reschedule message in Redis DB")
+	}
+	return nil
+}
+
+// Postpone откладывает доставку уведомления до fireAt: сохраняет статус и время срабатывания,
+// в историю записывается причина отсрочки
+func (rc *RedisConnection) Postpone(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error {
+	data, _ := json.Marshal(models.StatusChange{Status: status, At: time.Now(), Reason: reason, Until: fireAt})
+	// отсрочка не меняет статус для клиента, callback о ней не отправляется
+	err := setStatusScript.Run(ctx, rc.rdb, []string{uuid, historyKeyPrefix + uuid}, status, string(data), unixMilli(fireAt), "", 0).Err()
+	if err != nil {
+		return errors.New("Failed to postpone message in Redis DB")
+	}
+	return nil
+}
+
+// editMessageScript меняет содержимое и расписание уведомления в статусе ARGV[3],
+// если его версия равна ARGV[1]; ARGV[2] – новая версия, ARGV[4] – текущее время (мс),
+// дальше пары поле-значение. Новая версия расписания в той же операции ставит уведомление в outbox.
+// Возвращает {0, ""} если записи нет, {1, ""} при успехе, {2, статус} для неподходящего статуса
+// и {3, версия} если уведомление уже изменили.
+var editMessageScript = redis.NewScript(reindexLua + outboxLua + `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {0, ""}
+end
+local status = redis.call("HGET", KEYS[1], "status") or ""
+local version = redis.call("HGET", KEYS[1], "version") or "0"
+if status ~= ARGV[3] then
+	return {2, status}
+end
+if version ~= ARGV[1] then
+	return {3, version}
+end
+redis.call("HSET", KEYS[1], "version", ARGV[2], unpack(ARGV, 5))
+reindex(KEYS[1], status, status)
+if ARGV[2] ~= ARGV[1] then
+	requeue(KEYS[1], ARGV[4])
+end
+return {1, ""}
+`)
+
+// EditMessage сохраняет текст и расписание ожидающего уведомления и меняет версию с version на newVersion;
+// перенесённое (newVersion != version) уведомление в той же операции ставится в outbox, новое сообщение
+// публикует outbox relay. models.ErrStatusConflict – уведомление не ожидает отправки или его уже изменили.
+func (rc *RedisConnection) EditMessage(ctx context.Context, notif models.Notification, version int, newVersion int) error {
+	res, err := editMessageScript.Run(ctx, rc.rdb, []string{notif.UUID},
+		version, newVersion, models.StatusPending, time.Now().UnixMilli(),
+		"message", notif.Message,
+		"subject", notif.Subject,
+		"scheduled_at", notif.ScheduledAt,
+		"fire_at", unixMilli(notif.FireAt),
+		"timezone", notif.Timezone,
+	).Slice()
+	if err != nil || len(res) != 2 {
+		return errors.New("Failed to edit message in Redis DB")
+	}
+
+	code, _ := res[0].(int64)
+	current, _ := res[1].(string)
+	switch code {
+	case 0:
+		return models.ErrNotFound
+	case 1:
+		return nil
+	case 2:
+		return fmt.Errorf("%w: status is %q", models.ErrStatusConflict, current)
+	default:
+		return fmt.Errorf("%w: notification is modified concurrently (version %s)", models.ErrStatusConflict, current)
+	}
+}
+
+// resumeSeriesScript возобновляет приостановленную серию KEYS[1], если версия её расписания
+// равна ARGV[1]: переводит её в ожидание с новой версией и временем срабатывания ARGV[2] (мс),
+// пишет смену статуса ARGV[3] в историю KEYS[2] и ставит серию в outbox на ARGV[4] (мс).
+// Возвращает {0, ""} если записи нет, {1, ""} при успехе, {2, статус} для неподходящего статуса
+// и {3, версия} если расписание уже изменили.
+var resumeSeriesScript = redis.NewScript(reindexLua + eventsLua + outboxLua + `
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return {0, ""}
+end
+local status = redis.call("HGET", KEYS[1], "status") or ""
+local version = redis.call("HGET", KEYS[1], "version") or "0"
+if status ~= "` + models.StatusPaused + `" then
+	return {2, status}
+end
+if version ~= ARGV[1] then
+	return {3, version}
+end
+redis.call("HSET", KEYS[1], "status", "` + models.StatusPending + `", "version", tonumber(version) + 1, "fire_at", ARGV[2])
+redis.call("RPUSH", KEYS[2], ARGV[3])
+reindex(KEYS[1], status, "` + models.StatusPending + `")
+requeue(KEYS[1], ARGV[4])
+publishEvent(KEYS[1], ARGV[3])
+return {1, ""}
+`)
+
+// ResumeSeries возобновляет приостановленную серию с версией расписания version: переводит её
+// в ожидание со следующим срабатыванием fireAt и новой версией и в той же операции ставит в outbox.
+// models.ErrStatusConflict – серия не приостановлена или её расписание уже изменили.
+func (rc *RedisConnection) ResumeSeries(ctx context.Context, uuid string, version int, fireAt time.Time) error {
+	res, err := resumeSeriesScript.Run(ctx, rc.rdb, []string{uuid, historyKeyPrefix + uuid},
+		version, unixMilli(fireAt), statusChange(models.StatusPending), time.Now().UnixMilli()).Slice()
+	if err != nil || len(res) != 2 {
+		return errors.New("Failed to resume series in Redis DB")
+	}
+
+	code, _ := res[0].(int64)
+	current, _ := res[1].(string)
+	switch code {
+	case 0:
+		return models.ErrNotFound
+	case 1:
+		return nil
+	case 2:
+		return fmt.Errorf("%w: status is %q", models.ErrStatusConflict, current)
+	default:
+		return fmt.Errorf("%w: series is modified concurrently (version %s)", models.ErrStatusConflict, current)
+	}
+}
+
+// CompleteOccurrence засчитывает срабатывание серии и обнуляет счётчик попыток для следующего;
+// возвращает число завершённых срабатываний
+func (rc *RedisConnection) CompleteOccurrence(ctx context.Context, uuid string) (int, error) {
+	var occurrences *redis.IntCmd
+	_, err := rc.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		occurrences = pipe.HIncrBy(ctx, uuid, "occurrences", 1)
+		pipe.HSet(ctx, uuid, "attempts", 0)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.New("Failed to save occurrence into Redis DB")
+	}
+	return int(occurrences.Val()), nil
+}
+
+// ReserveIdempotencyKey занимает ключ идемпотентности на ttl. Если ключ уже занят,
+// возвращает сохранённую запись и false.
+func (rc *RedisConnection) ReserveIdempotencyKey(ctx context.Context, key string, fingerprint string, ttl time.Duration) (models.IdempotencyRecord, bool, error) {
+	record := models.IdempotencyRecord{Fingerprint: fingerprint}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+
+	reserved, err := rc.rdb.SetNX(ctx, idempotencyKeyPrefix+key, data, ttl).Result()
+	if err != nil {
+		return models.IdempotencyRecord{}, false, errors.New("Failed to reserve idempotency key in Redis DB")
+	}
+	if reserved {
+		return record, true, nil
+	}
+
+	stored, err := rc.rdb.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if err != nil {
+		return models.IdempotencyRecord{}, false, errors.New("Failed to get idempotency key from Redis DB")
+	}
+	if err := json.Unmarshal(stored, &record); err != nil {
+		return models.IdempotencyRecord{}, false, err
+	}
+	return record, false, nil
+}
+
+// SaveIdempotencyKey сохраняет ответ на запрос с ключом идемпотентности
+func (rc *RedisConnection) SaveIdempotencyKey(ctx context.Context, key string, record models.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := rc.rdb.Set(ctx, idempotencyKeyPrefix+key, data, ttl).Err(); err != nil {
+		return errors.New("Failed to save idempotency key into Redis DB")
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ после неудачного запроса, чтобы его можно было повторить
+func (rc *RedisConnection) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := rc.rdb.Del(ctx, idempotencyKeyPrefix+key).Err(); err != nil {
+		return errors.New("Failed to release idempotency key in Redis DB")
 	}
 	return nil
 }
@@ -0,0 +1,99 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// ZSET отложенных сообщений "uuid:version" или "uuid:version/priority", score – время доставки (мс)
+	scheduleKey = "notify:schedule"
+	// список наступивших сообщений, ожидающих публикации в очередь
+	readyKey = "notify:schedule:ready"
+)
+
+// scheduleMember кодирует сообщение; приоритет идёт после версии, где ":" и "/" не встречаются,
+// поэтому UUID может содержать любые символы
+func scheduleMember(message models.ScheduledMessage) string {
+	member := message.UUID + ":" + strconv.Itoa(message.Version)
+	if message.Priority != "" {
+		member += "/" + message.Priority
+	}
+	return member
+}
+
+func parseScheduleMember(member string) (models.ScheduledMessage, error) {
+	i := strings.LastIndex(member, ":")
+	if i < 0 {
+		return models.ScheduledMessage{}, errors.New("invalid scheduled message " + member)
+	}
+	version, priority, _ := strings.Cut(member[i+1:], "/")
+	number, err := strconv.Atoi(version)
+	if err != nil {
+		return models.ScheduledMessage{}, errors.New("invalid scheduled message " + member)
+	}
+	return models.ScheduledMessage{UUID: member[:i], Version: number, Priority: priority}, nil
+}
+
+// ScheduleMessage откладывает сообщение до at; повторное планирование той же версии переносит его
+func (rc *RedisConnection) ScheduleMessage(ctx context.Context, message models.ScheduledMessage, at time.Time) error {
+	err := rc.rdb.ZAdd(ctx, scheduleKey, redis.Z{Score: float64(at.UnixMilli()), Member: scheduleMember(message)}).Err()
+	if err != nil {
+		return errors.New("Failed to schedule message in Redis DB")
+	}
+	return nil
+}
+
+// promoteDueScript атомарно переносит до ARGV[2] сообщений со временем не позже ARGV[1]
+// из KEYS[1] в список KEYS[2]
+var promoteDueScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[2]))
+for _, member in ipairs(due) do
+	redis.call("ZREM", KEYS[1], member)
+	redis.call("RPUSH", KEYS[2], member)
+end
+return #due
+`)
+
+// PromoteDue переносит наступившие к now сообщения в список готовых к публикации
+func (rc *RedisConnection) PromoteDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	moved, err := promoteDueScript.Run(ctx, rc.rdb, []string{scheduleKey, readyKey}, now.UnixMilli(), limit).Int()
+	if err != nil {
+		return 0, errors.New("Failed to promote due messages in Redis DB")
+	}
+	return moved, nil
+}
+
+// ReadyMessages возвращает до limit готовых к публикации сообщений, не удаляя их из списка
+func (rc *RedisConnection) ReadyMessages(ctx context.Context, limit int) ([]models.ScheduledMessage, error) {
+	members, err := rc.rdb.LRange(ctx, readyKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, errors.New("Failed to get ready messages from Redis DB")
+	}
+
+	messages := make([]models.ScheduledMessage, 0, len(members))
+	for _, member := range members {
+		message, err := parseScheduleMember(member)
+		if err != nil {
+			// повреждённую запись публиковать нечего
+			rc.rdb.LRem(ctx, readyKey, 1, member)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// AckReady удаляет опубликованное сообщение из списка готовых
+func (rc *RedisConnection) AckReady(ctx context.Context, message models.ScheduledMessage) error {
+	if err := rc.rdb.LRem(ctx, readyKey, 1, scheduleMember(message)).Err(); err != nil {
+		return errors.New("Failed to ack ready message in Redis DB")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package redisdb
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// список версий шаблона, версия N – элемент с индексом N-1
+const templateKeyPrefix = "notify:template:"
+
+// SaveTemplate сохраняет новую версию шаблона и возвращает его с номером версии
+func (rc *RedisConnection) SaveTemplate(ctx context.Context, template models.Template) (models.Template, error) {
+	template.Version = 0
+	data, err := json.Marshal(template)
+	if err != nil {
+		return models.Template{}, err
+	}
+
+	version, err := rc.rdb.RPush(ctx, templateKeyPrefix+template.Name, data).Result()
+	if err != nil {
+		return models.Template{}, errors.New("Failed to save template into Redis DB")
+	}
+	template.Version = int(version)
+	return template, nil
+}
+
+// getTemplateScript возвращает {номер версии, шаблон}; ARGV[1] = 0 – последняя версия
+var getTemplateScript = redis.NewScript(`
+local version = tonumber(ARGV[1])
+if version == 0 then
+	version = redis.call("LLEN", KEYS[1])
+end
+local template = redis.call("LINDEX", KEYS[1], version - 1)
+if not template or version == 0 then
+	return {0, ""}
+end
+return {version, template}
+`)
+
+// GetTemplate загружает версию шаблона (0 – последнюю); models.ErrNotFound – если её нет
+func (rc *RedisConnection) GetTemplate(ctx context.Context, name string, version int) (models.Template, error) {
+	if version < 0 {
+		return models.Template{}, models.ErrNotFound
+	}
+	res, err := getTemplateScript.Run(ctx, rc.rdb, []string{templateKeyPrefix + name}, version).Slice()
+	if err != nil || len(res) != 2 {
+		return models.Template{}, errors.New("Failed to get template from Redis DB")
+	}
+
+	found, _ := res[0].(int64)
+	data, _ := res[1].(string)
+	if found == 0 {
+		return models.Template{}, models.ErrNotFound
+	}
+
+	var template models.Template
+	if err := json.Unmarshal([]byte(data), &template); err != nil {
+		return models.Template{}, errors.New("Failed to decode template from Redis DB")
+	}
+	template.Version = int(found)
+	return template, nil
+}
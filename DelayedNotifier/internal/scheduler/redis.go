@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Store interface for Redis operations used by the Redis scheduler
+type Store interface {
+	ScheduleMessage(ctx context.Context, message models.ScheduledMessage, at time.Time) error
+	PromoteDue(ctx context.Context, now time.Time, limit int) (int, error)
+	ReadyMessages(ctx context.Context, limit int) ([]models.ScheduledMessage, error)
+	AckReady(ctx context.Context, message models.ScheduledMessage) error
+}
+
+// Queue публикует сообщение в рабочую очередь без задержки
+type Queue interface {
+	SendMessage(notification models.Notification) error
+}
+
+// Redis планировщик без плагина отложенных сообщений: время доставки хранится в ZSET,
+// а Run каждые PollInterval атомарно переносит наступившие сообщения в список готовых
+// и публикует их в рабочую очередь. Сообщение удаляется из списка только после
+// подтверждённой публикации, поэтому сбой приводит к повторной публикации, а не к потере:
+// дубликат worker отбросит при захвате уведомления.
+type Redis struct {
+	store Store
+	queue Queue
+
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+func NewRedis(store Store, queue Queue, pollInterval time.Duration, batchSize int) *Redis {
+	return &Redis{
+		store:        store,
+		queue:        queue,
+		PollInterval: pollInterval,
+		BatchSize:    batchSize,
+	}
+}
+
+// SendMessage откладывает сообщение на notification.ScheduledAt мс
+func (s *Redis) SendMessage(notification models.Notification) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if notification.ScheduledAt > models.MaxScheduleDelay.Milliseconds() {
+		log.Printf("Failed to schedule a message %s: delay %d ms exceeds the limit", notification.UUID, notification.ScheduledAt)
+		return models.ErrDelayTooLong
+	}
+	at := time.Now().Add(time.Duration(notification.ScheduledAt) * time.Millisecond)
+	message := models.ScheduledMessage{UUID: notification.UUID, Version: notification.Version, Priority: notification.Priority}
+	if err := s.store.ScheduleMessage(ctx, message, at); err != nil {
+		log.Printf("Failed to schedule a message: %s", err)
+		return err
+	}
+	return nil
+}
+
+// CancelMessageDelay как и для delayed exchange только фиксирует отмену:
+// отложенное сообщение дойдёт до consumer-а, который пропустит отменённое уведомление
+func (s *Redis) CancelMessageDelay(messageId string) error {
+	log.Printf("Notification %s is cancelled, its scheduled message will be skipped by consumer", messageId)
+	return nil
+}
+
+// MaxDelay время доставки – score в ZSET, задержку ограничивает только models.MaxScheduleDelay
+func (s *Redis) MaxDelay() time.Duration {
+	return 0
+}
+
+// Run публикует наступившие сообщения каждые PollInterval до отмены ctx;
+// пока пачки заполнены целиком, следующая публикуется сразу
+func (s *Redis) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			published, err := s.Poll(ctx)
+			if err != nil {
+				log.Printf("Redis scheduler: %s", err)
+			}
+			if err != nil || published < s.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll переносит наступившие сообщения в список готовых и публикует одну пачку, начиная
+// с более приоритетных; возвращает число опубликованных. При сбое очереди остаток пачки
+// ждёт следующего опроса, неподтверждённое сообщение – тоже, чтобы Run не публиковал его снова
+// в том же цикле.
+func (s *Redis) Poll(ctx context.Context) (int, error) {
+	if _, err := s.store.PromoteDue(ctx, time.Now(), s.BatchSize); err != nil {
+		return 0, err
+	}
+
+	ready, err := s.store.ReadyMessages(ctx, s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	slices.SortStableFunc(ready, func(a, b models.ScheduledMessage) int {
+		return models.PriorityLevel(b.Priority) - models.PriorityLevel(a.Priority)
+	})
+
+	published := 0
+	for _, message := range ready {
+		notification := models.Notification{UUID: message.UUID, Version: message.Version}
+		notification.Priority = message.Priority
+		err := s.queue.SendMessage(notification)
+		if err != nil {
+			return published, err
+		}
+		published++
+		if err := s.store.AckReady(ctx, message); err != nil {
+			return published, fmt.Errorf("failed to ack scheduled message %s: %w", message.UUID, err)
+		}
+	}
+	return published, nil
+}
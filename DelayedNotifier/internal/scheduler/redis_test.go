@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Mock Store для тестирования: ZSET и список готовых сообщений в памяти
+type MockStore struct {
+	scheduled map[models.ScheduledMessage]time.Time
+	ready     []models.ScheduledMessage
+}
+
+func (m *MockStore) ScheduleMessage(ctx context.Context, message models.ScheduledMessage, at time.Time) error {
+	m.scheduled[message] = at
+	return nil
+}
+
+func (m *MockStore) PromoteDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	moved := 0
+	for message, at := range m.scheduled {
+		if !at.After(now) && moved < limit {
+			delete(m.scheduled, message)
+			m.ready = append(m.ready, message)
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *MockStore) ReadyMessages(ctx context.Context, limit int) ([]models.ScheduledMessage, error) {
+	return m.ready[:min(limit, len(m.ready))], nil
+}
+
+func (m *MockStore) AckReady(ctx context.Context, message models.ScheduledMessage) error {
+	for i, ready := range m.ready {
+		if ready == message {
+			m.ready = append(m.ready[:i:i], m.ready[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Mock Queue для тестирования: падает после failAfter публикаций (отрицательное – никогда)
+type MockQueue struct {
+	failAfter int
+	sent      []models.Notification
+}
+
+func (m *MockQueue) SendMessage(notification models.Notification) error {
+	if m.failAfter >= 0 && len(m.sent) >= m.failAfter {
+		return errors.New("queue connection failed")
+	}
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+func TestRedis_Poll(t *testing.T) {
+	tests := []struct {
+		name              string
+		failAfter         int
+		expectedPublished int
+		expectedReady     int
+	}{
+		{name: "Due messages are published", failAfter: -1, expectedPublished: 2, expectedReady: 0},
+		{name: "Queue failure keeps the rest ready", failAfter: 1, expectedPublished: 1, expectedReady: 1},
+		{name: "Queue is down", failAfter: 0, expectedPublished: 0, expectedReady: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{scheduled: map[models.ScheduledMessage]time.Time{}}
+			queue := &MockQueue{failAfter: tt.failAfter}
+			s := NewRedis(store, queue, time.Second, 10)
+
+			for _, n := range []models.Notification{
+				{UUID: "due-1", Version: 1},
+				{UUID: "due-2"},
+				{UUID: "later", NotificationCard: models.NotificationCard{ScheduledAt: 60000}},
+			} {
+				if err := s.SendMessage(n); err != nil {
+					t.Fatalf("Failed to schedule %s: %v", n.UUID, err)
+				}
+			}
+
+			published, err := s.Poll(context.Background())
+			if (err != nil) != (tt.failAfter >= 0) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if published != tt.expectedPublished || len(queue.sent) != tt.expectedPublished {
+				t.Errorf("Expected %d published, got %d", tt.expectedPublished, published)
+			}
+			if len(store.ready) != tt.expectedReady {
+				t.Errorf("Expected %d ready messages, got %d", tt.expectedReady, len(store.ready))
+			}
+			if len(store.scheduled) != 1 {
+				t.Errorf("Expected only the later message to stay scheduled, got %d", len(store.scheduled))
+			}
+			for _, n := range queue.sent {
+				if n.UUID == "due-1" && n.Version != 1 {
+					t.Errorf("Expected version 1 to be published, got %d", n.Version)
+				}
+			}
+		})
+	}
+}
+
+func TestRedis_PollPriority(t *testing.T) {
+	store := &MockStore{scheduled: map[models.ScheduledMessage]time.Time{}}
+	queue := &MockQueue{failAfter: -1}
+	s := NewRedis(store, queue, time.Second, 10)
+
+	for _, priority := range []string{models.PriorityLow, "", models.PriorityUrgent, models.PriorityHigh} {
+		n := models.Notification{UUID: "due-" + priority}
+		n.Priority = priority
+		if err := s.SendMessage(n); err != nil {
+			t.Fatalf("Failed to schedule %s: %v", n.UUID, err)
+		}
+	}
+
+	if _, err := s.Poll(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{models.PriorityUrgent, models.PriorityHigh, "", models.PriorityLow}
+	if len(queue.sent) != len(expected) {
+		t.Fatalf("Expected %d published, got %d", len(expected), len(queue.sent))
+	}
+	for i, priority := range expected {
+		if queue.sent[i].Priority != priority {
+			t.Errorf("Expected priority %q at %d, got %q", priority, i, queue.sent[i].Priority)
+		}
+	}
+}
+
+func TestRedis_RunDrainsBacklog(t *testing.T) {
+	store := &MockStore{scheduled: map[models.ScheduledMessage]time.Time{}}
+	queue := &MockQueue{failAfter: -1}
+	// опрос раз в час: весь накопившийся backlog должен уйти в первом же цикле
+	s := NewRedis(store, queue, time.Hour, 10)
+	for i := range 25 {
+		store.ScheduleMessage(context.Background(), models.ScheduledMessage{UUID: fmt.Sprint("n-", i)}, time.Now().Add(-time.Second))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	if len(queue.sent) != 25 || len(store.ready) != 0 || len(store.scheduled) != 0 {
+		t.Errorf("Expected 25 published messages, got %d, ready %d, scheduled %d", len(queue.sent), len(store.ready), len(store.scheduled))
+	}
+}
+
+// TestRedis_SendMessageDelayLimit tests that a delay overflowing time.Duration is rejected
+func TestRedis_SendMessageDelayLimit(t *testing.T) {
+	store := &MockStore{scheduled: map[models.ScheduledMessage]time.Time{}}
+	s := NewRedis(store, &MockQueue{failAfter: -1}, time.Second, 10)
+
+	n := models.Notification{UUID: "far", NotificationCard: models.NotificationCard{ScheduledAt: 9300000000000}}
+	if err := s.SendMessage(n); !errors.Is(err, models.ErrDelayTooLong) {
+		t.Errorf("Expected ErrDelayTooLong, got %v", err)
+	}
+	if len(store.scheduled) != 0 {
+		t.Errorf("Expected nothing scheduled, got %v", store.scheduled)
+	}
+}
@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/rabbitMQ"
+	"time"
+)
+
+// Бэкенды планировщика (config.Scheduler.Backend)
+const (
+	BackendRabbitMQ = "rabbitmq" // задержку держит плагин rabbitmq-delayed-message-exchange
+	BackendRedis    = "redis"    // задержку держит ZSET в Redis, см. Redis
+	BackendMemory   = "memory"   // задержку держит колесо таймеров в памяти, см. memory.Queue
+)
+
+// Scheduler откладывает сообщение уведомления до его времени и передаёт его в рабочую очередь.
+// Реализации: rabbitMQ.QueueProps (delayed exchange), Redis и memory.Queue.
+type Scheduler interface {
+	SendMessage(notification models.Notification) error
+	CancelMessageDelay(messageId string) error
+	// MaxDelay наибольшая задержка, на которую можно отложить сообщение; 0 – без ограничения
+	MaxDelay() time.Duration
+}
+
+var (
+	_ Scheduler = (*rabbitMQ.QueueProps)(nil)
+	_ Scheduler = (*Redis)(nil)
+)
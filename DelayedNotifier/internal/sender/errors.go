@@ -0,0 +1,35 @@
+package sender
+
+import (
+	"errors"
+	"time"
+)
+
+// RetryableError временная ошибка доставки: уведомление можно отправить повторно
+type RetryableError struct {
+	Err error
+	// RetryAfter пауза перед повтором, запрошенная провайдером; 0 – на усмотрение воркера
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable помечает ошибку доставки как временную
+func Retryable(err error, retryAfter time.Duration) error {
+	return &RetryableError{Err: err, RetryAfter: retryAfter}
+}
+
+// IsRetryable сообщает, временная ли ошибка, и паузу перед повтором
+func IsRetryable(err error) (time.Duration, bool) {
+	var retryable *RetryableError
+	if errors.As(err, &retryable) {
+		return retryable.RetryAfter, true
+	}
+	return 0, false
+}
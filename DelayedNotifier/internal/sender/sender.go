@@ -0,0 +1,55 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"fmt"
+	"log"
+)
+
+// Sender доставляет уведомление по одному каналу
+type Sender interface {
+	Send(ctx context.Context, notification models.Notification) error
+}
+
+// Registry выбирает Sender по полю Channel уведомления
+type Registry struct {
+	senders map[string]Sender
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		senders: map[string]Sender{
+			models.ChannelLog: Log{},
+		},
+	}
+}
+
+// Register добавляет (или заменяет) канал доставки
+func (r *Registry) Register(channel string, s Sender) {
+	r.senders[channel] = s
+}
+
+// Send доставляет уведомление через канал из notification.Channel;
+// уведомления без канала пишутся в лог.
+func (r *Registry) Send(ctx context.Context, notification models.Notification) error {
+	channel := notification.Channel
+	if channel == "" {
+		channel = models.ChannelLog
+	}
+
+	s, ok := r.senders[channel]
+	if !ok {
+		return fmt.Errorf("unknown delivery channel %q", channel)
+	}
+
+	return s.Send(ctx, notification)
+}
+
+// Log "доставляет" уведомление записью в лог
+type Log struct{}
+
+func (Log) Send(ctx context.Context, notification models.Notification) error {
+	log.Printf("Notification %s: %s", notification.UUID, notification.Message)
+	return nil
+}
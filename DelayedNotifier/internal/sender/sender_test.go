@@ -0,0 +1,44 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"testing"
+)
+
+// Mock Sender для тестирования
+type MockSender struct {
+	sent []models.Notification
+}
+
+func (m *MockSender) Send(ctx context.Context, notification models.Notification) error {
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+// TestRegistry_Send tests channel dispatching
+func TestRegistry_Send(t *testing.T) {
+	email := &MockSender{}
+	registry := NewRegistry()
+	registry.Register(models.ChannelEmail, email)
+
+	notification := models.Notification{UUID: "test-uuid"}
+
+	// Без канала уведомление уходит в лог
+	if err := registry.Send(context.Background(), notification); err != nil {
+		t.Errorf("Expected log channel by default, got %v", err)
+	}
+
+	notification.Channel = models.ChannelEmail
+	if err := registry.Send(context.Background(), notification); err != nil {
+		t.Errorf("Send failed: %v", err)
+	}
+	if len(email.sent) != 1 {
+		t.Errorf("Expected 1 email, got %d", len(email.sent))
+	}
+
+	notification.Channel = "pigeon"
+	if err := registry.Send(context.Background(), notification); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+}
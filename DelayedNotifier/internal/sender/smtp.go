@@ -0,0 +1,184 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+const (
+	SecurityNone     = "none"
+	SecurityStartTLS = "starttls"
+	SecurityTLS      = "tls"
+
+	defaultSubject = "Notification"
+	defaultTimeout = 10 * time.Second
+)
+
+// SMTP отправляет уведомления письмом; Recipient уведомления – адрес получателя
+type SMTP struct {
+	cfg config.SMTP
+}
+
+func NewSMTP(cfg config.SMTP) *SMTP {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &SMTP{cfg: cfg}
+}
+
+func (s *SMTP) Send(ctx context.Context, notification models.Notification) error {
+	if notification.Recipient == "" {
+		return errors.New("email recipient is empty")
+	}
+
+	return classifySMTPError(s.send(ctx, notification))
+}
+
+func (s *SMTP) send(ctx context.Context, notification models.Notification) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.cfg.Security == SecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	if err := client.Rcpt(notification.Recipient); err != nil {
+		return fmt.Errorf("smtp rcpt to: %w", err)
+	}
+
+	wc, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err := wc.Write(s.buildMessage(notification)); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.Security == SecurityTLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig()}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp dial: %w", err)
+	}
+
+	// Весь SMTP диалог ограничен таймаутом или дедлайном контекста
+	deadline := time.Now().Add(s.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smtp greeting: %w", err)
+	}
+
+	return client, nil
+}
+
+// classifySMTPError помечает временными ответы 4xx и сетевые ошибки
+func classifySMTPError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		if protoErr.Code >= 400 && protoErr.Code < 500 {
+			return Retryable(err, 0)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return Retryable(err, 0)
+	}
+
+	return err
+}
+
+func (s *SMTP) tlsConfig() *tls.Config {
+	return &tls.Config{
+		ServerName:         s.cfg.Host,
+		InsecureSkipVerify: s.cfg.InsecureSkipVerify,
+	}
+}
+
+func (s *SMTP) buildMessage(notification models.Notification) []byte {
+	subject := notification.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.cfg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", notification.Recipient)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@delayed-notifier>\r\n", notification.UUID)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if notification.Format == models.TemplateFormatHTML {
+		buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	} else {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	}
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(notification.Message))
+	_ = qp.Close()
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
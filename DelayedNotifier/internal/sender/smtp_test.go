@@ -0,0 +1,302 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receivedMail письмо, принятое тестовым SMTP сервером
+type receivedMail struct {
+	From string
+	To   []string
+	Auth string
+	TLS  bool
+	Data string
+}
+
+// smtpStandIn минимальный in-process SMTP сервер для тестов
+type smtpStandIn struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	rejectTo  string
+
+	mu    sync.Mutex
+	mails []receivedMail
+}
+
+func newSMTPStandIn(t *testing.T, tlsConfig *tls.Config) *smtpStandIn {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &smtpStandIn{listener: ln, tlsConfig: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpStandIn) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *smtpStandIn) received() []receivedMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]receivedMail(nil), s.mails...)
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	var current receivedMail
+	reply("220 stand-in ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-stand-in")
+			if s.tlsConfig != nil && !current.TLS {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case cmd == "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+			current.TLS = true
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			current.Auth = strings.TrimSpace(line[len("AUTH PLAIN"):])
+			reply("235 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			current.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			to := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			if to == s.rejectTo {
+				reply("550 No such user")
+				continue
+			}
+			current.To = append(current.To, to)
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			current.Data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, current)
+			s.mu.Unlock()
+			reply("250 OK: queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// selfSignedTLSConfig генерирует сертификат для STARTTLS в тестах
+func selfSignedTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+}
+
+// TestSMTP_Send tests delivery to the in-process SMTP stand-in
+func TestSMTP_Send(t *testing.T) {
+	tests := []struct {
+		name      string
+		security  string
+		username  string
+		password  string
+		expectTLS bool
+	}{
+		{
+			name:     "Plain connection without auth",
+			security: SecurityNone,
+		},
+		{
+			name:     "Plain auth",
+			security: SecurityNone,
+			username: "notifier",
+			password: "secret",
+		},
+		{
+			name:      "STARTTLS with auth",
+			security:  SecurityStartTLS,
+			username:  "notifier",
+			password:  "secret",
+			expectTLS: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tlsConfig *tls.Config
+			if tt.security == SecurityStartTLS {
+				tlsConfig = selfSignedTLSConfig(t)
+			}
+			server := newSMTPStandIn(t, tlsConfig)
+
+			s := NewSMTP(config.SMTP{
+				Host:               "127.0.0.1",
+				Port:               server.port(),
+				Username:           tt.username,
+				Password:           tt.password,
+				From:               "notifier@example.com",
+				Security:           tt.security,
+				InsecureSkipVerify: true,
+				Timeout:            5 * time.Second,
+			})
+
+			notification := models.Notification{
+				UUID: "test-uuid",
+				NotificationCard: models.NotificationCard{
+					Message:   "Привет, the meeting starts in 15 minutes",
+					Channel:   models.ChannelEmail,
+					Recipient: "user@example.com",
+					Subject:   "Напоминание",
+				},
+			}
+			if err := s.Send(context.Background(), notification); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+
+			mails := server.received()
+			if len(mails) != 1 {
+				t.Fatalf("Expected 1 mail, got %d", len(mails))
+			}
+			got := mails[0]
+
+			if got.From != "notifier@example.com" {
+				t.Errorf("Expected sender 'notifier@example.com', got '%s'", got.From)
+			}
+			if len(got.To) != 1 || got.To[0] != "user@example.com" {
+				t.Errorf("Expected recipient 'user@example.com', got %v", got.To)
+			}
+			if got.TLS != tt.expectTLS {
+				t.Errorf("Expected TLS %v, got %v", tt.expectTLS, got.TLS)
+			}
+
+			if tt.username != "" {
+				creds, _ := base64.StdEncoding.DecodeString(got.Auth)
+				if string(creds) != "\x00"+tt.username+"\x00"+tt.password {
+					t.Errorf("Unexpected AUTH PLAIN credentials %q", creds)
+				}
+			}
+
+			msg, err := mail.ReadMessage(strings.NewReader(got.Data))
+			if err != nil {
+				t.Fatalf("Failed to parse received mail: %v", err)
+			}
+			subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if subject != "Напоминание" {
+				t.Errorf("Expected subject 'Напоминание', got '%s'", subject)
+			}
+			body, _ := io.ReadAll(msg.Body)
+			if !strings.Contains(string(body), "the meeting starts in 15 minutes") {
+				t.Errorf("Unexpected body: %s", body)
+			}
+		})
+	}
+}
+
+// TestSMTP_SendRejectedRecipient tests that SMTP errors are returned to the worker
+func TestSMTP_SendRejectedRecipient(t *testing.T) {
+	server := newSMTPStandIn(t, nil)
+	server.rejectTo = "unknown@example.com"
+
+	s := NewSMTP(config.SMTP{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		From:     "notifier@example.com",
+		Security: SecurityNone,
+	})
+
+	notification := models.Notification{
+		UUID: "test-uuid",
+		NotificationCard: models.NotificationCard{
+			Message:   "Test message",
+			Channel:   models.ChannelEmail,
+			Recipient: "unknown@example.com",
+		},
+	}
+	if err := s.Send(context.Background(), notification); err == nil {
+		t.Error("Expected error for rejected recipient")
+	}
+	if len(server.received()) != 0 {
+		t.Error("Mail should not be delivered")
+	}
+}
@@ -0,0 +1,119 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultTelegramBaseURL = "https://api.telegram.org"
+
+// Telegram отправляет уведомления через Bot API; Recipient уведомления – chat_id
+type Telegram struct {
+	cfg    config.Telegram
+	client *http.Client
+}
+
+func NewTelegram(cfg config.Telegram) *Telegram {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultTelegramBaseURL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Telegram{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+type telegramLinkPreview struct {
+	IsDisabled bool `json:"is_disabled"`
+}
+
+type telegramMessage struct {
+	ChatID             string               `json:"chat_id"`
+	Text               string               `json:"text"`
+	ParseMode          string               `json:"parse_mode,omitempty"`
+	LinkPreviewOptions *telegramLinkPreview `json:"link_preview_options,omitempty"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (t *Telegram) Send(ctx context.Context, notification models.Notification) error {
+	if notification.Recipient == "" {
+		return errors.New("telegram chat_id is empty")
+	}
+
+	msg := telegramMessage{
+		ChatID:    notification.Recipient,
+		Text:      notification.Message,
+		ParseMode: t.cfg.ParseMode,
+	}
+	if t.cfg.DisablePreview {
+		msg.LinkPreviewOptions = &telegramLinkPreview{IsDisabled: true}
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(t.cfg.BaseURL, "/") + "/bot" + t.cfg.Token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// сетевые ошибки и таймауты – временные; токен не должен попасть в лог вместе с URL
+		return Retryable(errors.New("telegram request failed: "+redactToken(err.Error(), t.cfg.Token)), 0)
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode < http.StatusInternalServerError {
+		return fmt.Errorf("telegram: failed to decode response with status %d: %w", resp.StatusCode, err)
+	}
+	if result.OK {
+		return nil
+	}
+
+	code := result.ErrorCode
+	if code == 0 {
+		code = resp.StatusCode
+	}
+	apiErr := fmt.Errorf("telegram: %d %s", code, result.Description)
+
+	switch {
+	case code == http.StatusTooManyRequests:
+		return Retryable(apiErr, time.Duration(result.Parameters.RetryAfter)*time.Second)
+	case code >= http.StatusInternalServerError:
+		return Retryable(apiErr, 0)
+	default:
+		return apiErr
+	}
+}
+
+func redactToken(s, token string) string {
+	if token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, token, "<token>")
+}
@@ -0,0 +1,122 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestTelegram_Send tests Bot API requests and error mapping against an httptest stand-in
+func TestTelegram_Send(t *testing.T) {
+	tests := []struct {
+		name               string
+		statusCode         int
+		response           string
+		expectErr          bool
+		expectRetryable    bool
+		expectedRetryAfter time.Duration
+	}{
+		{
+			name:       "Successful send",
+			statusCode: http.StatusOK,
+			response:   `{"ok":true,"result":{"message_id":1}}`,
+		},
+		{
+			name:               "Too many requests is retryable",
+			statusCode:         http.StatusTooManyRequests,
+			response:           `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`,
+			expectErr:          true,
+			expectRetryable:    true,
+			expectedRetryAfter: 7 * time.Second,
+		},
+		{
+			name:            "Server error is retryable",
+			statusCode:      http.StatusBadGateway,
+			response:        `<html>Bad Gateway</html>`,
+			expectErr:       true,
+			expectRetryable: true,
+		},
+		{
+			name:       "Blocked by user is permanent",
+			statusCode: http.StatusForbidden,
+			response:   `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`,
+			expectErr:  true,
+		},
+		{
+			name:       "Bad request is permanent",
+			statusCode: http.StatusBadRequest,
+			response:   `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`,
+			expectErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]interface{}
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			s := NewTelegram(config.Telegram{
+				Token:          "123:secret",
+				BaseURL:        server.URL,
+				ParseMode:      "HTML",
+				DisablePreview: true,
+			})
+
+			err := s.Send(context.Background(), models.Notification{
+				UUID: "test-uuid",
+				NotificationCard: models.NotificationCard{
+					Message:   "<b>On-call</b> shift starts",
+					Channel:   models.ChannelTelegram,
+					Recipient: "-100200300",
+				},
+			})
+
+			if gotPath != "/bot123:secret/sendMessage" {
+				t.Errorf("Unexpected request path '%s'", gotPath)
+			}
+			if got["chat_id"] != "-100200300" || got["text"] != "<b>On-call</b> shift starts" || got["parse_mode"] != "HTML" {
+				t.Errorf("Unexpected request body %v", got)
+			}
+			if preview, _ := got["link_preview_options"].(map[string]interface{}); preview["is_disabled"] != true {
+				t.Errorf("Expected link preview to be disabled, got %v", got["link_preview_options"])
+			}
+
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, got %v", tt.expectErr, err)
+			}
+			retryAfter, retryable := IsRetryable(err)
+			if retryable != tt.expectRetryable {
+				t.Errorf("Expected retryable: %v, got %v (%v)", tt.expectRetryable, retryable, err)
+			}
+			if retryAfter != tt.expectedRetryAfter {
+				t.Errorf("Expected retry after %s, got %s", tt.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+// TestTelegram_SendUnreachable tests that connection errors are retryable
+func TestTelegram_SendUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	s := NewTelegram(config.Telegram{Token: "123:secret", BaseURL: server.URL})
+	err := s.Send(context.Background(), models.Notification{
+		NotificationCard: models.NotificationCard{Recipient: "42"},
+	})
+	if _, ok := IsRetryable(err); !ok {
+		t.Errorf("Expected retryable error, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Заголовки подписи исходящего вебхука
+const (
+	HeaderWebhookTimestamp = "X-Notifier-Timestamp"
+	HeaderWebhookSignature = "X-Notifier-Signature"
+)
+
+// Webhook отправляет уведомление POST запросом с JSON телом на URL из Recipient.
+// Тело подписывается HMAC-SHA256 от "<timestamp>.<body>", чтобы получатель мог
+// проверить подлинность и отбросить повторно отправленные старые запросы.
+type Webhook struct {
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+type webhookPayload struct {
+	ID        string `json:"id"`
+	Channel   string `json:"channel"`
+	Subject   string `json:"subject,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func NewWebhook(cfg config.Webhook) *Webhook {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Webhook{
+		secret: []byte(cfg.Secret),
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// редиректы не выполняем: подпись привязана к исходному получателю
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+func (wh *Webhook) Send(ctx context.Context, notification models.Notification) error {
+	target, err := url.Parse(notification.Recipient)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("invalid webhook url %q", notification.Recipient)
+	}
+
+	timestamp := wh.now().Unix()
+	body, err := json.Marshal(webhookPayload{
+		ID:        notification.UUID,
+		Channel:   models.ChannelWebhook,
+		Subject:   notification.Subject,
+		Message:   notification.Message,
+		Timestamp: timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(timestamp, 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookTimestamp, ts)
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(wh.secret, ts, body))
+
+	resp, err := wh.client.Do(req)
+	if err != nil {
+		// таймауты и ошибки соединения – временные
+		return Retryable(fmt.Errorf("webhook request failed: %w", err), 0)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	statusErr := fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode >= http.StatusInternalServerError:
+		return Retryable(statusErr, parseRetryAfter(resp.Header.Get("Retry-After"), wh.now()))
+	default:
+		return statusErr
+	}
+}
+
+// SignWebhook возвращает значение заголовка X-Notifier-Signature для тела запроса
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook проверяет подпись вебхука на стороне получателя; запросы
+// старше tolerance отклоняются как повторные.
+func VerifyWebhook(secret []byte, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	age := now.Sub(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.New("webhook timestamp is outside of tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(SignWebhook(secret, timestamp, body))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
+
+// parseRetryAfter разбирает заголовок Retry-After (секунды или HTTP дата)
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package sender
+
+import (
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/models"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestWebhook_Send tests signed delivery and classification of receiver responses
+func TestWebhook_Send(t *testing.T) {
+	secret := []byte("webhook-secret")
+
+	tests := []struct {
+		name               string
+		statusCode         int
+		retryAfter         string
+		delay              time.Duration
+		expectErr          bool
+		expectRetryable    bool
+		expectedRetryAfter time.Duration
+	}{
+		{
+			name:       "Accepted by receiver",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:            "Server error is retryable",
+			statusCode:      http.StatusServiceUnavailable,
+			expectErr:       true,
+			expectRetryable: true,
+		},
+		{
+			name:               "Too many requests with Retry-After",
+			statusCode:         http.StatusTooManyRequests,
+			retryAfter:         "120",
+			expectErr:          true,
+			expectRetryable:    true,
+			expectedRetryAfter: 2 * time.Minute,
+		},
+		{
+			name:       "Client error is permanent",
+			statusCode: http.StatusGone,
+			expectErr:  true,
+		},
+		{
+			name:       "Redirect is not followed",
+			statusCode: http.StatusTemporaryRedirect,
+			expectErr:  true,
+		},
+		{
+			name:            "Timeout is retryable",
+			statusCode:      http.StatusOK,
+			delay:           500 * time.Millisecond,
+			expectErr:       true,
+			expectRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type received struct {
+				payload   webhookPayload
+				verifyErr error
+			}
+			receivedCh := make(chan received, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var got received
+				body, _ := io.ReadAll(r.Body)
+				got.verifyErr = VerifyWebhook(secret, r.Header.Get(HeaderWebhookTimestamp), r.Header.Get(HeaderWebhookSignature), body, 5*time.Minute, time.Now())
+				_ = json.Unmarshal(body, &got.payload)
+				receivedCh <- got
+
+				time.Sleep(tt.delay)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				if tt.statusCode == http.StatusTemporaryRedirect {
+					w.Header().Set("Location", "/elsewhere")
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			wh := NewWebhook(config.Webhook{Secret: string(secret), Timeout: 200 * time.Millisecond})
+			err := wh.Send(context.Background(), models.Notification{
+				UUID: "test-uuid",
+				NotificationCard: models.NotificationCard{
+					Message:   "Deploy window opens",
+					Channel:   models.ChannelWebhook,
+					Recipient: server.URL + "/hooks/notify",
+				},
+			})
+
+			got := <-receivedCh
+			if got.verifyErr != nil {
+				t.Errorf("Receiver failed to verify signature: %v", got.verifyErr)
+			}
+			if got.payload.ID != "test-uuid" || got.payload.Message != "Deploy window opens" {
+				t.Errorf("Unexpected payload %+v", got.payload)
+			}
+
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error: %v, got %v", tt.expectErr, err)
+			}
+			retryAfter, retryable := IsRetryable(err)
+			if retryable != tt.expectRetryable {
+				t.Errorf("Expected retryable: %v, got %v (%v)", tt.expectRetryable, retryable, err)
+			}
+			if retryAfter != tt.expectedRetryAfter {
+				t.Errorf("Expected retry after %s, got %s", tt.expectedRetryAfter, retryAfter)
+			}
+		})
+	}
+}
+
+// TestVerifyWebhook tests receiver side verification
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("webhook-secret")
+	body := []byte(`{"id":"test-uuid"}`)
+	now := time.Unix(1700000000, 0)
+	ts := "1700000000"
+	signature := SignWebhook(secret, ts, body)
+
+	if err := VerifyWebhook(secret, ts, signature, body, time.Minute, now); err != nil {
+		t.Errorf("Expected valid signature, got %v", err)
+	}
+	if err := VerifyWebhook(secret, ts, signature, []byte(`{"id":"other"}`), time.Minute, now); err == nil {
+		t.Error("Expected error for tampered body")
+	}
+	if err := VerifyWebhook([]byte("other-secret"), ts, signature, body, time.Minute, now); err == nil {
+		t.Error("Expected error for wrong secret")
+	}
+	if err := VerifyWebhook(secret, ts, signature, body, time.Minute, now.Add(10*time.Minute)); err == nil {
+		t.Error("Expected error for replayed request")
+	}
+}
+
+// TestWebhook_SendInvalidURL tests that a malformed URL is a permanent failure
+func TestWebhook_SendInvalidURL(t *testing.T) {
+	wh := NewWebhook(config.Webhook{Secret: "secret"})
+	err := wh.Send(context.Background(), models.Notification{
+		NotificationCard: models.NotificationCard{Recipient: "ftp://example.com"},
+	})
+	if err == nil {
+		t.Fatal("Expected error for invalid url")
+	}
+	if _, ok := IsRetryable(err); ok {
+		t.Error("Invalid url should not be retryable")
+	}
+}
@@ -0,0 +1,93 @@
+package templates
+
+import (
+	"DelayedNotifier/internal/models"
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"regexp"
+	texttemplate "text/template"
+)
+
+// namePattern допустимое имя шаблона: используется в пути /templates/{name}
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// textExecutor и htmlExecutor приводят Execute обоих пакетов к одному виду
+type textExecutor struct{ t *texttemplate.Template }
+
+func (e textExecutor) Execute(w io.Writer, data any) error { return e.t.Execute(w, data) }
+
+type htmlExecutor struct{ t *htmltemplate.Template }
+
+func (e htmlExecutor) Execute(w io.Writer, data any) error { return e.t.Execute(w, data) }
+
+// Validate проверяет имя, формат и синтаксис шаблона
+func Validate(t models.Template) error {
+	if !namePattern.MatchString(t.Name) {
+		return errors.New("name must be 1-100 characters of letters, digits, '_', '.' or '-'")
+	}
+	if t.Body == "" {
+		return errors.New("body must not be empty")
+	}
+	_, _, err := parse(t)
+	return err
+}
+
+// parse разбирает тему (всегда text/template) и текст в формате шаблона;
+// отсутствующая в data переменная – ошибка рендера, а не "<no value>"
+func parse(t models.Template) (executor, executor, error) {
+	subject, err := texttemplate.New("subject").Option("missingkey=error").Parse(t.Subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid subject: %w", err)
+	}
+
+	switch t.Format {
+	case models.TemplateFormatText, "":
+		body, err := texttemplate.New("body").Option("missingkey=error").Parse(t.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid body: %w", err)
+		}
+		return textExecutor{subject}, textExecutor{body}, nil
+	case models.TemplateFormatHTML:
+		body, err := htmltemplate.New("body").Option("missingkey=error").Parse(t.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid body: %w", err)
+		}
+		return textExecutor{subject}, htmlExecutor{body}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q, expected %q or %q", t.Format, models.TemplateFormatText, models.TemplateFormatHTML)
+	}
+}
+
+// Render подставляет data в шаблон и возвращает тему и текст сообщения.
+// Ошибка – если нет обязательной переменной или шаблон обращается к отсутствующей.
+func Render(t models.Template, data map[string]any) (string, string, error) {
+	for _, name := range t.Variables {
+		if _, ok := data[name]; !ok {
+			return "", "", fmt.Errorf("missing required variable %q", name)
+		}
+	}
+
+	subject, body, err := parse(t)
+	if err != nil {
+		return "", "", err
+	}
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	var subjectBuf, bodyBuf bytes.Buffer
+	if err := subject.Execute(&subjectBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := body.Execute(&bodyBuf, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return subjectBuf.String(), bodyBuf.String(), nil
+}
@@ -0,0 +1,99 @@
+package templates
+
+import (
+	"DelayedNotifier/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name            string
+		template        models.Template
+		data            map[string]any
+		expectedSubject string
+		expectedBody    string
+		expectedErr     string
+	}{
+		{
+			name: "Text template",
+			template: models.Template{
+				Name: "welcome", Format: models.TemplateFormatText,
+				Subject: "Hello, {{.name}}", Body: "Your code is {{.code}}",
+			},
+			data:            map[string]any{"name": "Ann", "code": 42},
+			expectedSubject: "Hello, Ann",
+			expectedBody:    "Your code is 42",
+		},
+		{
+			name: "HTML template escapes values",
+			template: models.Template{
+				Name: "welcome", Format: models.TemplateFormatHTML,
+				Body: "<p>{{.name}}</p>",
+			},
+			data:         map[string]any{"name": "<b>Ann</b>"},
+			expectedBody: "<p>&lt;b&gt;Ann&lt;/b&gt;</p>",
+		},
+		{
+			name: "Missing required variable",
+			template: models.Template{
+				Name: "welcome", Body: "Hello", Variables: []string{"name"},
+			},
+			data:        map[string]any{},
+			expectedErr: `missing required variable "name"`,
+		},
+		{
+			name: "Missing referenced variable",
+			template: models.Template{
+				Name: "welcome", Body: "Hello, {{.name}}",
+			},
+			expectedErr: "failed to render body",
+		},
+		{
+			name: "Unknown format",
+			template: models.Template{
+				Name: "welcome", Format: "markdown", Body: "Hello",
+			},
+			expectedErr: "unknown format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body, err := Render(tt.template, tt.data)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if subject != tt.expectedSubject || body != tt.expectedBody {
+				t.Errorf("Expected %q / %q, got %q / %q", tt.expectedSubject, tt.expectedBody, subject, body)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template models.Template
+		valid    bool
+	}{
+		{name: "Valid", template: models.Template{Name: "order.shipped", Body: "{{.id}}"}, valid: true},
+		{name: "Invalid name", template: models.Template{Name: "a/b", Body: "x"}},
+		{name: "Empty body", template: models.Template{Name: "empty"}},
+		{name: "Syntax error", template: models.Template{Name: "broken", Body: "{{.id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.template); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
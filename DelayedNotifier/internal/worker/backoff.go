@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy бюджет и экспоненциальная задержка повторных доставок
+type RetryPolicy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// MaxAttempts число попыток доставки по умолчанию, включая первую
+	MaxAttempts int
+}
+
+// Delay возвращает паузу перед следующей попыткой после attempt неудачных.
+// Задержка растёт как BaseDelay*2^(attempt-1), ограничена MaxDelay (0 – без ограничения)
+// и случайно уменьшается до половины (jitter), чтобы повторы не шли пачкой.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		// без MaxDelay удвоение упирается в максимальную длительность, а не переполняется
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
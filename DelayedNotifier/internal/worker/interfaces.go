@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"DelayedNotifier/internal/models"
+	"context"
+	"time"
+)
+
+// Store interface for Redis operations used by the worker
+type Store interface {
+	GetMessage(ctx context.Context, uuid string) (models.Notification, error)
+	SaveStatus(ctx context.Context, uuid string, status string) error
+	TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error)
+	SaveAttempt(ctx context.Context, uuid string, attempt models.Attempt) error
+	IncrAttempts(ctx context.Context, uuid string) (int, error)
+	MarkDead(ctx context.Context, uuid string, at time.Time) error
+	Reschedule(ctx context.Context, uuid string, status string, fireAt time.Time) error
+	Postpone(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error
+	CompleteOccurrence(ctx context.Context, uuid string) (int, error)
+	GetTemplate(ctx context.Context, name string, version int) (models.Template, error)
+	GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error)
+}
+
+// Queue interface for RabbitMQ operations used by the worker
+type Queue interface {
+	SendMessage(notification models.Notification) error
+	// MaxDelay потолок задержки планировщика; 0 – без ограничения
+	MaxDelay() time.Duration
+}
+
+// Sender доставляет уведомление получателю
+type Sender interface {
+	Send(ctx context.Context, notification models.Notification) error
+}
+
+// Limiter ограничивает частоту доставок: возвращает 0, если уведомление можно отправить сейчас,
+// или задержку, на которую доставку нужно отложить
+type Limiter interface {
+	Reserve(ctx context.Context, notification models.Notification) (time.Duration, error)
+}
@@ -0,0 +1,295 @@
+package worker
+
+import (
+	"DelayedNotifier/internal/cron"
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/quiethours"
+	"DelayedNotifier/internal/sender"
+	"DelayedNotifier/internal/templates"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrDeadLetter возвращается Handle для окончательно неудачной доставки:
+// сообщение отклоняется без возврата в очередь и уходит в dead-letter очередь
+var ErrDeadLetter = errors.New("notification is dead-lettered")
+
+// dueTolerance допустимое опережение доставки относительно fire_at. Более раннее сообщение
+// без версии расписания считается устаревшим и пропускается.
+const dueTolerance = time.Second
+
+type Worker struct {
+	store   Store
+	queue   Queue
+	sender  Sender
+	retry   RetryPolicy
+	limiter Limiter
+}
+
+// New создаёт worker; limiter nil – без ограничения частоты доставок
+func New(store Store, queue Queue, sender Sender, retry RetryPolicy, limiter Limiter) *Worker {
+	return &Worker{
+		store:   store,
+		queue:   queue,
+		sender:  sender,
+		retry:   retry,
+		limiter: limiter,
+	}
+}
+
+// Handle обрабатывает одно наступившее уведомление по его UUID.
+// Ошибка возвращается только при сбое хранилища или очереди, чтобы сообщение вернулось в очередь;
+// захваченное уведомление при этом возвращается в прежний статус, а не остаётся в processing;
+// временная ошибка канала переводит уведомление в StatusRetrying с повторной публикацией,
+// пока не исчерпан бюджет попыток; остальные ошибки доставки фиксируются статусом StatusFailed
+// и возвращают ErrDeadLetter. После доставки повторяющегося уведомления планируется следующее
+// срабатывание серии; окончательная ошибка останавливает серию вместе с уведомлением.
+// Сообщение с версией расписания, отличной от сохранённой (уведомление перенесли), отбрасывается;
+// version < 0 – сообщение без версии. Доставка в тихие часы получателя (кроме срочных уведомлений)
+// и сверх лимитов Limiter откладывается повторной публикацией.
+func (w *Worker) Handle(ctx context.Context, uuid string, version int) error {
+	notification, err := w.store.GetMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		log.Printf("Notification %s is not found, skip it", uuid)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 0 && version != notification.Version {
+		log.Printf("Notification %s is rescheduled (version %d, message version %d), skip stale message", uuid, notification.Version, version)
+		return nil
+	}
+	if notification.FireAt.After(time.Now().Add(dueTolerance)) {
+		log.Printf("Notification %s is not due until %s, skip stale message", uuid, notification.FireAt)
+		return nil
+	}
+
+	// захват уведомления атомарен: отменённое (cancelled) или уже обрабатываемое пропускается
+	previous, err := w.store.TransitionStatus(ctx, uuid, models.StatusProcessing, models.StatusPending, models.StatusRetrying)
+	if errors.Is(err, models.ErrStatusConflict) || errors.Is(err, models.ErrNotFound) {
+		log.Printf("Notification %s is skipped: %s", uuid, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// доставка в тихие часы или сверх лимита канала или получателя откладывается,
+	// попыткой она не считается; токены лимита в тихие часы не расходуются
+	if until, ok := w.quietUntil(ctx, notification); ok {
+		return w.postpone(ctx, notification, previous, time.Until(until), models.ReasonQuietHours)
+	}
+	if wait := w.reserve(ctx, notification); wait > 0 {
+		return w.postpone(ctx, notification, previous, wait, models.ReasonRateLimit)
+	}
+
+	attempts, err := w.store.IncrAttempts(ctx, uuid)
+	if err != nil {
+		return w.release(ctx, uuid, previous, err)
+	}
+
+	err = w.render(ctx, &notification)
+	if err == nil {
+		err = w.sender.Send(ctx, notification)
+	}
+	if err == nil {
+		if notification.Cron != "" {
+			return w.scheduleNext(ctx, notification)
+		}
+		return w.store.SaveStatus(ctx, uuid, models.StatusSent)
+	}
+
+	// причина неудачи остаётся в истории попыток уведомления
+	attempt := models.Attempt{Number: attempts, At: time.Now(), Error: err.Error()}
+	if err := w.store.SaveAttempt(ctx, uuid, attempt); err != nil {
+		return w.release(ctx, uuid, previous, err)
+	}
+
+	retryAfter, ok := sender.IsRetryable(err)
+	if !ok {
+		return w.fail(ctx, uuid, err)
+	}
+
+	maxAttempts := notification.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = w.retry.MaxAttempts
+	}
+	if attempts >= maxAttempts {
+		return w.fail(ctx, uuid, fmt.Errorf("retry budget of %d attempts is exhausted: %w", maxAttempts, err))
+	}
+
+	delay := w.retry.Delay(attempts)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return w.scheduleRetry(ctx, notification, delay, err)
+}
+
+// render подставляет данные уведомления в его шаблон. Удалённый шаблон или ошибка рендера –
+// окончательная ошибка доставки, сбой хранилища – временная.
+func (w *Worker) render(ctx context.Context, notification *models.Notification) error {
+	if notification.Template == "" {
+		return nil
+	}
+
+	template, err := w.store.GetTemplate(ctx, notification.Template, notification.TemplateVersion)
+	if errors.Is(err, models.ErrNotFound) {
+		return fmt.Errorf("template %q version %d is not found", notification.Template, notification.TemplateVersion)
+	}
+	if err != nil {
+		return sender.Retryable(err, 0)
+	}
+
+	subject, body, err := templates.Render(template, notification.Data)
+	if err != nil {
+		return fmt.Errorf("template %q: %w", notification.Template, err)
+	}
+	if subject != "" {
+		notification.Subject = subject
+	}
+	notification.Message = body
+	notification.Format = template.Format
+	return nil
+}
+
+// release возвращает захваченное уведомление из StatusProcessing в статус previous после сбоя
+// хранилища, чтобы повторно доставленное сообщение снова его захватило, и возвращает cause
+func (w *Worker) release(ctx context.Context, uuid string, previous string, cause error) error {
+	if _, err := w.store.TransitionStatus(ctx, uuid, previous, models.StatusProcessing); err != nil {
+		log.Printf("Failed to release notification %s back to %s: %s", uuid, previous, err)
+	}
+	return cause
+}
+
+// fail переводит уведомление в StatusFailed и отправляет сообщение в dead-letter очередь
+func (w *Worker) fail(ctx context.Context, uuid string, cause error) error {
+	log.Printf("Failed to deliver notification %s: %s", uuid, cause)
+	if err := w.store.SaveStatus(ctx, uuid, models.StatusFailed); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", ErrDeadLetter, cause)
+}
+
+// HandleDead обрабатывает сообщение из dead-letter очереди: уведомление
+// попадает в индекс, по которому работают GET /notify/dead и replay.
+// Сообщение устаревшей версии расписания не трогает перенесённое уведомление.
+func (w *Worker) HandleDead(ctx context.Context, uuid string, version int) error {
+	notification, err := w.store.GetMessage(ctx, uuid)
+	if errors.Is(err, models.ErrNotFound) {
+		log.Printf("Dead notification %s is not found, skip it", uuid)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 0 && version != notification.Version {
+		log.Printf("Dead message of notification %s is stale (version %d), skip it", uuid, version)
+		return nil
+	}
+
+	// в DLQ попадают и сообщения, которые не удалось обработать из-за сбоев хранилища
+	if notification.Status != models.StatusFailed {
+		if err := w.store.SaveStatus(ctx, uuid, models.StatusFailed); err != nil {
+			return err
+		}
+	}
+
+	return w.store.MarkDead(ctx, uuid, time.Now())
+}
+
+// reserve забирает токены лимитов доставки. Сбой хранилища лимитов не останавливает доставку:
+// уведомление уже захвачено, и возврат сообщения в очередь оставил бы его в processing
+func (w *Worker) reserve(ctx context.Context, notification models.Notification) time.Duration {
+	if w.limiter == nil {
+		return 0
+	}
+	wait, err := w.limiter.Reserve(ctx, notification)
+	if err != nil {
+		log.Printf("Failed to check rate limit of notification %s, deliver it: %s", notification.UUID, err)
+		return 0
+	}
+	return wait
+}
+
+// quietUntil возвращает конец тихих часов получателя, если они идут сейчас.
+// Срочные уведомления доставляются без отсрочки; сбой хранилища настроек, как и сбой лимитов,
+// доставку не останавливает
+func (w *Worker) quietUntil(ctx context.Context, notification models.Notification) (time.Time, bool) {
+	if notification.Priority == models.PriorityUrgent || notification.Recipient == "" {
+		return time.Time{}, false
+	}
+	channel := notification.Channel
+	if channel == "" {
+		channel = models.ChannelLog
+	}
+
+	prefs, err := w.store.GetPreferences(ctx, channel, notification.Recipient)
+	if errors.Is(err, models.ErrNotFound) {
+		return time.Time{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to get preferences of notification %s recipient, deliver it: %s", notification.UUID, err)
+		return time.Time{}, false
+	}
+	return quiethours.Until(prefs, time.Now())
+}
+
+// postpone возвращает уведомление в статус status и публикует его снова с задержкой wait;
+// reason – причина отсрочки для истории уведомления
+func (w *Worker) postpone(ctx context.Context, notification models.Notification, status string, wait time.Duration, reason string) error {
+	log.Printf("Notification %s is postponed for %s: %s", notification.UUID, wait, reason)
+
+	if err := w.store.Postpone(ctx, notification.UUID, status, time.Now().Add(wait), reason); err != nil {
+		return w.release(ctx, notification.UUID, status, err)
+	}
+
+	notification.ScheduledAt = wait.Milliseconds()
+	return w.queue.SendMessage(notification)
+}
+
+// scheduleRetry откладывает повторную доставку через delayedExchange
+func (w *Worker) scheduleRetry(ctx context.Context, notification models.Notification, delay time.Duration, cause error) error {
+	log.Printf("Delivery of notification %s failed temporarily, retry in %s: %s", notification.UUID, delay, cause)
+
+	if err := w.store.Reschedule(ctx, notification.UUID, models.StatusRetrying, time.Now().Add(delay)); err != nil {
+		return err
+	}
+
+	notification.ScheduledAt = delay.Milliseconds()
+	return w.queue.SendMessage(notification)
+}
+
+// scheduleNext публикует следующее срабатывание серии; завершённая серия получает StatusSent
+func (w *Worker) scheduleNext(ctx context.Context, notification models.Notification) error {
+	occurrences, err := w.store.CompleteOccurrence(ctx, notification.UUID)
+	if err != nil {
+		return err
+	}
+	notification.Occurrences = occurrences
+
+	now := time.Now()
+	next, ok, err := cron.NextOccurrence(notification, now)
+	if err != nil {
+		return w.fail(ctx, notification.UUID, fmt.Errorf("invalid series schedule: %w", err))
+	}
+	if !ok {
+		log.Printf("Series %s is finished after %d occurrences", notification.UUID, occurrences)
+		return w.store.SaveStatus(ctx, notification.UUID, models.StatusSent)
+	}
+
+	delay := next.Sub(now)
+	if maxDelay := w.queue.MaxDelay(); maxDelay > 0 && delay > maxDelay {
+		return w.fail(ctx, notification.UUID, fmt.Errorf("next occurrence at %s exceeds the delay limit", next))
+	}
+
+	if err := w.store.Reschedule(ctx, notification.UUID, models.StatusPending, next); err != nil {
+		return err
+	}
+
+	notification.ScheduledAt = delay.Milliseconds()
+	return w.queue.SendMessage(notification)
+}
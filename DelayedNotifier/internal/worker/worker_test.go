@@ -0,0 +1,833 @@
+package worker
+
+import (
+	"DelayedNotifier/internal/models"
+	"DelayedNotifier/internal/sender"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// Mock Store для тестирования
+type MockStore struct {
+	GetMessageFunc     func(ctx context.Context, uuid string) (models.Notification, error)
+	GetTemplateFunc    func(ctx context.Context, name string, version int) (models.Template, error)
+	GetPreferencesFunc func(ctx context.Context, channel string, address string) (models.Preferences, error)
+	TransitionFunc     func(ctx context.Context, uuid string, to string, from ...string) (string, error)
+	IncrAttemptsFunc   func(ctx context.Context, uuid string) (int, error)
+	SaveAttemptFunc    func(ctx context.Context, uuid string, attempt models.Attempt) error
+	PostponeFunc       func(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error
+	statuses           []string
+	lastError          string
+	attempts           int
+	occurrences        int
+	fireAt             time.Time
+	reasons            []string
+	dead               []string
+}
+
+func (m *MockStore) GetMessage(ctx context.Context, uuid string) (models.Notification, error) {
+	if m.GetMessageFunc != nil {
+		return m.GetMessageFunc(ctx, uuid)
+	}
+	return models.Notification{UUID: uuid, Status: models.StatusPending}, nil
+}
+
+func (m *MockStore) GetTemplate(ctx context.Context, name string, version int) (models.Template, error) {
+	if m.GetTemplateFunc != nil {
+		return m.GetTemplateFunc(ctx, name, version)
+	}
+	return models.Template{}, models.ErrNotFound
+}
+
+func (m *MockStore) GetPreferences(ctx context.Context, channel string, address string) (models.Preferences, error) {
+	if m.GetPreferencesFunc != nil {
+		return m.GetPreferencesFunc(ctx, channel, address)
+	}
+	return models.Preferences{}, models.ErrNotFound
+}
+
+func (m *MockStore) SaveStatus(ctx context.Context, uuid string, status string) error {
+	m.statuses = append(m.statuses, status)
+	return nil
+}
+
+func (m *MockStore) TransitionStatus(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+	if m.TransitionFunc != nil {
+		return m.TransitionFunc(ctx, uuid, to, from...)
+	}
+	current, err := m.GetMessage(ctx, uuid)
+	if err != nil {
+		return "", err
+	}
+	for _, status := range from {
+		if current.Status == status {
+			m.statuses = append(m.statuses, to)
+			return current.Status, nil
+		}
+	}
+	return current.Status, models.ErrStatusConflict
+}
+
+func (m *MockStore) SaveAttempt(ctx context.Context, uuid string, attempt models.Attempt) error {
+	if m.SaveAttemptFunc != nil {
+		return m.SaveAttemptFunc(ctx, uuid, attempt)
+	}
+	m.lastError = attempt.Error
+	return nil
+}
+
+func (m *MockStore) MarkDead(ctx context.Context, uuid string, at time.Time) error {
+	m.dead = append(m.dead, uuid)
+	return nil
+}
+
+func (m *MockStore) Reschedule(ctx context.Context, uuid string, status string, fireAt time.Time) error {
+	m.statuses = append(m.statuses, status)
+	m.fireAt = fireAt
+	return nil
+}
+
+func (m *MockStore) Postpone(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error {
+	if m.PostponeFunc != nil {
+		return m.PostponeFunc(ctx, uuid, status, fireAt, reason)
+	}
+	m.statuses = append(m.statuses, status)
+	m.fireAt = fireAt
+	m.reasons = append(m.reasons, reason)
+	return nil
+}
+
+func (m *MockStore) CompleteOccurrence(ctx context.Context, uuid string) (int, error) {
+	m.occurrences++
+	m.attempts = 0
+	return m.occurrences, nil
+}
+
+func (m *MockStore) IncrAttempts(ctx context.Context, uuid string) (int, error) {
+	if m.IncrAttemptsFunc != nil {
+		return m.IncrAttemptsFunc(ctx, uuid)
+	}
+	m.attempts++
+	return m.attempts, nil
+}
+
+// Mock Queue для тестирования
+type MockQueue struct {
+	sent     []models.Notification
+	maxDelay time.Duration
+}
+
+func (m *MockQueue) MaxDelay() time.Duration {
+	return m.maxDelay
+}
+
+func (m *MockQueue) SendMessage(notification models.Notification) error {
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+// Mock Limiter для тестирования
+type MockLimiter struct {
+	wait time.Duration
+	err  error
+}
+
+func (m *MockLimiter) Reserve(ctx context.Context, notification models.Notification) (time.Duration, error) {
+	return m.wait, m.err
+}
+
+// Mock Sender для тестирования
+type MockSender struct {
+	SendFunc func(ctx context.Context, notification models.Notification) error
+}
+
+func (m *MockSender) Send(ctx context.Context, notification models.Notification) error {
+	if m.SendFunc != nil {
+		return m.SendFunc(ctx, notification)
+	}
+	return nil
+}
+
+// TestWorker_Handle tests status transitions of a due notification
+func TestWorker_Handle(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           string
+		attempts         int
+		maxAttempts      int
+		getErr           error
+		sendErr          error
+		expectErr        bool
+		expectDeadLetter bool
+		expectedStatuses []string
+		expectedDelay    int64 // задержка повторной публикации, мс; 0 – без повтора
+		exactDelay       bool
+		fireIn           time.Duration
+		messageVersion   int // версия расписания в сообщении; сохранённая версия – 2
+	}{
+		{
+			name:             "Successful delivery",
+			status:           models.StatusPending,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+		},
+		{
+			name:             "Failed delivery",
+			status:           models.StatusPending,
+			sendErr:          errors.New("smtp is down"),
+			expectDeadLetter: true,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+		},
+		{
+			name:             "Retryable failure is republished",
+			status:           models.StatusPending,
+			sendErr:          sender.Retryable(errors.New("429 Too Many Requests"), 0),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusRetrying},
+			expectedDelay:    1000,
+		},
+		{
+			name:             "Retry delay grows with attempts",
+			status:           models.StatusRetrying,
+			attempts:         2,
+			sendErr:          sender.Retryable(errors.New("503 Service Unavailable"), 0),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusRetrying},
+			expectedDelay:    4000,
+		},
+		{
+			name:             "Retry budget is exhausted",
+			status:           models.StatusRetrying,
+			attempts:         2,
+			sendErr:          sender.Retryable(errors.New("503 Service Unavailable"), 0),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+			expectDeadLetter: true,
+			maxAttempts:      3,
+		},
+		{
+			name:             "Per-notification budget of one attempt",
+			status:           models.StatusPending,
+			sendErr:          sender.Retryable(errors.New("503 Service Unavailable"), 0),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+			expectDeadLetter: true,
+			maxAttempts:      1,
+		},
+		{
+			name:             "Retry after from provider is respected",
+			status:           models.StatusPending,
+			sendErr:          sender.Retryable(errors.New("429 Too Many Requests"), 5*time.Second),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusRetrying},
+			expectedDelay:    5000,
+			// задержка провайдера не уменьшается jitter-ом
+			exactDelay: true,
+		},
+		{
+			name:             "Retrying notification is delivered",
+			status:           models.StatusRetrying,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+		},
+		{
+			name:             "Already sent notification is skipped",
+			status:           models.StatusSent,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Cancelled notification is skipped",
+			status:           models.StatusCancelled,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Notification processed by another worker is skipped",
+			status:           models.StatusProcessing,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Stale schedule version is skipped",
+			status:           models.StatusPending,
+			messageVersion:   1,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Message without version is delivered",
+			status:           models.StatusPending,
+			messageVersion:   -1,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+		},
+		{
+			name:             "Message ahead of fire_at is skipped",
+			status:           models.StatusPending,
+			fireIn:           time.Hour,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Unknown notification is skipped",
+			getErr:           models.ErrNotFound,
+			expectedStatuses: nil,
+		},
+		{
+			name:             "Storage failure is returned",
+			getErr:           errors.New("redis connection failed"),
+			expectErr:        true,
+			expectedStatuses: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					notification := models.Notification{UUID: uuid, Status: tt.status, Attempts: tt.attempts, Version: 2}
+					notification.MaxAttempts = tt.maxAttempts
+					if tt.fireIn != 0 {
+						notification.FireAt = time.Now().Add(tt.fireIn)
+					}
+					return notification, tt.getErr
+				},
+				attempts: tt.attempts,
+			}
+			queue := &MockQueue{}
+			mockSender := &MockSender{
+				SendFunc: func(ctx context.Context, notification models.Notification) error {
+					return tt.sendErr
+				},
+			}
+
+			// без jitter-а проверить точную задержку нельзя, поэтому проверяется диапазон [d/2, d]
+			policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, MaxAttempts: 5}
+			version := tt.messageVersion
+			if version == 0 {
+				version = 2
+			}
+			err := New(store, queue, mockSender, policy, nil).Handle(context.Background(), "test-uuid", version)
+			if (err != nil) != (tt.expectErr || tt.expectDeadLetter) {
+				t.Errorf("Expected error: %v, got %v", tt.expectErr || tt.expectDeadLetter, err)
+			}
+			if errors.Is(err, ErrDeadLetter) != tt.expectDeadLetter {
+				t.Errorf("Expected dead letter: %v, got %v", tt.expectDeadLetter, err)
+			}
+
+			if len(store.statuses) != len(tt.expectedStatuses) {
+				t.Fatalf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+			}
+			for i := range tt.expectedStatuses {
+				if store.statuses[i] != tt.expectedStatuses[i] {
+					t.Errorf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+				}
+			}
+
+			if tt.sendErr != nil && store.lastError != tt.sendErr.Error() {
+				t.Errorf("Expected last error '%s', got '%s'", tt.sendErr, store.lastError)
+			}
+
+			if tt.expectedDelay == 0 && len(queue.sent) != 0 {
+				t.Errorf("Expected no republish, got %d", len(queue.sent))
+			}
+			if tt.expectedDelay != 0 {
+				if len(queue.sent) != 1 {
+					t.Fatalf("Expected 1 republish, got %d", len(queue.sent))
+				}
+				if got := queue.sent[0].ScheduledAt; tt.exactDelay && got != tt.expectedDelay {
+					t.Errorf("Expected delay %d ms, got %d", tt.expectedDelay, got)
+				} else if got < tt.expectedDelay/2 || got > tt.expectedDelay {
+					t.Errorf("Expected delay within [%d, %d] ms, got %d", tt.expectedDelay/2, tt.expectedDelay, got)
+				}
+			}
+		})
+	}
+}
+
+// TestWorker_HandleSeries tests scheduling of the next occurrence of a recurring notification
+func TestWorker_HandleSeries(t *testing.T) {
+	tests := []struct {
+		name             string
+		occurrences      int
+		maxOccurrences   int
+		until            time.Time
+		maxDelay         time.Duration
+		expectedStatuses []string
+		expectNext       bool
+		expectDeadLetter bool
+	}{
+		{
+			name:             "Next occurrence is published",
+			expectedStatuses: []string{models.StatusProcessing, models.StatusPending},
+			expectNext:       true,
+		},
+		{
+			name:             "Series ends after max occurrences",
+			occurrences:      2,
+			maxOccurrences:   3,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+		},
+		{
+			name:             "Series ends at until",
+			until:            time.Now().Add(-time.Minute),
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+		},
+		{
+			name:             "Next occurrence beyond scheduler limit",
+			maxDelay:         time.Second,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+			expectDeadLetter: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					notification := models.Notification{UUID: uuid, Status: models.StatusPending, Occurrences: tt.occurrences}
+					notification.Cron = "*/5 * * * *"
+					notification.MaxOccurrences = tt.maxOccurrences
+					notification.Until = tt.until
+					return notification, nil
+				},
+				occurrences: tt.occurrences,
+			}
+			queue := &MockQueue{maxDelay: tt.maxDelay}
+
+			err := New(store, queue, &MockSender{}, RetryPolicy{MaxAttempts: 1}, nil).Handle(context.Background(), "series-uuid", 0)
+			if errors.Is(err, ErrDeadLetter) != tt.expectDeadLetter || (err != nil && !tt.expectDeadLetter) {
+				t.Fatalf("Expected dead letter: %v, got %v", tt.expectDeadLetter, err)
+			}
+
+			if len(store.statuses) != len(tt.expectedStatuses) {
+				t.Fatalf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+			}
+			for i := range tt.expectedStatuses {
+				if store.statuses[i] != tt.expectedStatuses[i] {
+					t.Errorf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+				}
+			}
+			if store.occurrences != tt.occurrences+1 {
+				t.Errorf("Expected %d occurrences, got %d", tt.occurrences+1, store.occurrences)
+			}
+
+			if !tt.expectNext {
+				if len(queue.sent) != 0 {
+					t.Errorf("Expected no next occurrence, got %d", len(queue.sent))
+				}
+				return
+			}
+			if len(queue.sent) != 1 {
+				t.Fatalf("Expected next occurrence to be published, got %d", len(queue.sent))
+			}
+			if delay := queue.sent[0].ScheduledAt; delay <= 0 || delay > (5*time.Minute).Milliseconds() {
+				t.Errorf("Expected delay within 5 minutes, got %d ms", delay)
+			}
+			if store.fireAt.Minute()%5 != 0 || store.fireAt.Second() != 0 {
+				t.Errorf("Expected fire_at on a 5 minute boundary, got %s", store.fireAt)
+			}
+		})
+	}
+}
+
+// TestRetryPolicy_Delay tests exponential growth and the delay ceiling
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 2, max: 2 * time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 4, max: 8 * time.Second},
+		{attempt: 5, max: 10 * time.Second},
+		{attempt: 40, max: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			delay := policy.Delay(tt.attempt)
+			if delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("Attempt %d: expected delay within [%s, %s], got %s", tt.attempt, tt.max/2, tt.max, delay)
+			}
+		}
+	}
+}
+
+// TestRetryPolicy_DelayUnbounded tests growth without MaxDelay and the overflow guard
+func TestRetryPolicy_DelayUnbounded(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second}
+
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{attempt: 1, max: time.Second},
+		{attempt: 3, max: 4 * time.Second},
+		{attempt: 11, max: 1024 * time.Second},
+		{attempt: 1000, max: math.MaxInt64},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			delay := policy.Delay(tt.attempt)
+			if delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("Attempt %d: expected delay within [%s, %s], got %s", tt.attempt, tt.max/2, tt.max, delay)
+			}
+		}
+	}
+}
+
+// TestWorker_HandleDead tests indexing of dead-lettered notifications
+func TestWorker_HandleDead(t *testing.T) {
+	store := &MockStore{
+		GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+			// сообщение отклонено после повторных сбоев хранилища
+			return models.Notification{UUID: uuid, Status: models.StatusProcessing}, nil
+		},
+	}
+
+	w := New(store, &MockQueue{}, &MockSender{}, RetryPolicy{}, nil)
+	if err := w.HandleDead(context.Background(), "test-uuid", -1); err != nil {
+		t.Fatalf("HandleDead failed: %v", err)
+	}
+
+	if len(store.dead) != 1 || store.dead[0] != "test-uuid" {
+		t.Errorf("Expected notification to be marked dead, got %v", store.dead)
+	}
+	if len(store.statuses) != 1 || store.statuses[0] != models.StatusFailed {
+		t.Errorf("Expected status 'failed', got %v", store.statuses)
+	}
+}
+
+// TestWorker_HandleTemplate tests rendering of a templated notification at delivery
+func TestWorker_HandleTemplate(t *testing.T) {
+	tests := []struct {
+		name             string
+		data             map[string]any
+		templateErr      error
+		expectDeadLetter bool
+		expectedStatuses []string
+		expectedMessage  string
+	}{
+		{
+			name:             "Template is rendered",
+			data:             map[string]any{"name": "Alice"},
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+			expectedMessage:  "Hello, Alice!",
+		},
+		{
+			name:             "Missing variable fails delivery",
+			expectDeadLetter: true,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+		},
+		{
+			name:             "Deleted template fails delivery",
+			data:             map[string]any{"name": "Alice"},
+			templateErr:      models.ErrNotFound,
+			expectDeadLetter: true,
+			expectedStatuses: []string{models.StatusProcessing, models.StatusFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					notification := models.Notification{UUID: uuid, Status: models.StatusPending}
+					notification.Template = "welcome"
+					notification.TemplateVersion = 2
+					notification.Data = tt.data
+					return notification, nil
+				},
+				GetTemplateFunc: func(ctx context.Context, name string, version int) (models.Template, error) {
+					if name != "welcome" || version != 2 {
+						t.Errorf("Expected template welcome version 2, got %s version %d", name, version)
+					}
+					return models.Template{
+						Name:      name,
+						Version:   version,
+						Format:    models.TemplateFormatText,
+						Subject:   "Welcome",
+						Body:      "Hello, {{.name}}!",
+						Variables: []string{"name"},
+					}, tt.templateErr
+				},
+			}
+			var sent models.Notification
+			mockSender := &MockSender{
+				SendFunc: func(ctx context.Context, notification models.Notification) error {
+					sent = notification
+					return nil
+				},
+			}
+
+			err := New(store, &MockQueue{}, mockSender, RetryPolicy{MaxAttempts: 5}, nil).Handle(context.Background(), "test-uuid", -1)
+			if errors.Is(err, ErrDeadLetter) != tt.expectDeadLetter {
+				t.Errorf("Expected dead letter: %v, got %v", tt.expectDeadLetter, err)
+			}
+			if len(store.statuses) != len(tt.expectedStatuses) || store.statuses[len(store.statuses)-1] != tt.expectedStatuses[len(tt.expectedStatuses)-1] {
+				t.Errorf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+			}
+			if sent.Message != tt.expectedMessage {
+				t.Errorf("Expected message %q, got %q", tt.expectedMessage, sent.Message)
+			}
+			if tt.expectedMessage != "" && (sent.Subject != "Welcome" || sent.Format != models.TemplateFormatText) {
+				t.Errorf("Expected subject and format from template, got %q and %q", sent.Subject, sent.Format)
+			}
+		})
+	}
+}
+
+// TestWorker_HandleRateLimited tests postponing of deliveries over the rate limit
+func TestWorker_HandleRateLimited(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           string
+		limiter          *MockLimiter
+		expectedStatuses []string
+		expectSend       bool
+		expectPostpone   bool
+	}{
+		{
+			name:             "Delivery within limit",
+			status:           models.StatusPending,
+			limiter:          &MockLimiter{},
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+			expectSend:       true,
+		},
+		{
+			name:             "Pending delivery is postponed",
+			status:           models.StatusPending,
+			limiter:          &MockLimiter{wait: 3 * time.Second},
+			expectedStatuses: []string{models.StatusProcessing, models.StatusPending},
+			expectPostpone:   true,
+		},
+		{
+			name:             "Retrying delivery keeps its status",
+			status:           models.StatusRetrying,
+			limiter:          &MockLimiter{wait: 3 * time.Second},
+			expectedStatuses: []string{models.StatusProcessing, models.StatusRetrying},
+			expectPostpone:   true,
+		},
+		{
+			name:             "Limiter failure does not block delivery",
+			status:           models.StatusPending,
+			limiter:          &MockLimiter{err: errors.New("redis connection failed")},
+			expectedStatuses: []string{models.StatusProcessing, models.StatusSent},
+			expectSend:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					return models.Notification{UUID: uuid, Status: tt.status}, nil
+				},
+			}
+			queue := &MockQueue{}
+			sent := false
+			mockSender := &MockSender{
+				SendFunc: func(ctx context.Context, notification models.Notification) error {
+					sent = true
+					return nil
+				},
+			}
+
+			err := New(store, queue, mockSender, RetryPolicy{MaxAttempts: 5}, tt.limiter).Handle(context.Background(), "test-uuid", -1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fmt.Sprint(store.statuses) != fmt.Sprint(tt.expectedStatuses) {
+				t.Errorf("Expected statuses %v, got %v", tt.expectedStatuses, store.statuses)
+			}
+			if sent != tt.expectSend {
+				t.Errorf("Expected send: %v, got %v", tt.expectSend, sent)
+			}
+			if tt.expectPostpone {
+				if len(queue.sent) != 1 || queue.sent[0].ScheduledAt != 3000 {
+					t.Fatalf("Expected republish in 3000 ms, got %v", queue.sent)
+				}
+				// отложенная доставка не расходует бюджет попыток
+				if store.attempts != 0 {
+					t.Errorf("Expected no attempts, got %d", store.attempts)
+				}
+			}
+		})
+	}
+}
+
+// TestWorker_HandleReleasesClaim tests that a store failure after the claim returns the notification
+// to its previous status instead of leaving it in processing
+func TestWorker_HandleReleasesClaim(t *testing.T) {
+	storeErr := errors.New("redis connection failed")
+
+	tests := []struct {
+		name    string
+		status  string
+		limiter *MockLimiter
+		send    error
+		setup   func(store *MockStore)
+	}{
+		{
+			name:    "Attempts counter fails",
+			status:  models.StatusPending,
+			limiter: &MockLimiter{},
+			setup: func(store *MockStore) {
+				store.IncrAttemptsFunc = func(ctx context.Context, uuid string) (int, error) { return 0, storeErr }
+			},
+		},
+		{
+			name:    "Postpone fails",
+			status:  models.StatusRetrying,
+			limiter: &MockLimiter{wait: 3 * time.Second},
+			setup: func(store *MockStore) {
+				store.PostponeFunc = func(ctx context.Context, uuid string, status string, fireAt time.Time, reason string) error {
+					return storeErr
+				}
+			},
+		},
+		{
+			name:    "Attempt history fails",
+			status:  models.StatusPending,
+			limiter: &MockLimiter{},
+			send:    sender.Retryable(errors.New("smtp unavailable"), 0),
+			setup: func(store *MockStore) {
+				store.SaveAttemptFunc = func(ctx context.Context, uuid string, attempt models.Attempt) error { return storeErr }
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := tt.status
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					return models.Notification{UUID: uuid, Status: status}, nil
+				},
+				TransitionFunc: func(ctx context.Context, uuid string, to string, from ...string) (string, error) {
+					previous := status
+					for _, s := range from {
+						if s == previous {
+							status = to
+							return previous, nil
+						}
+					}
+					return previous, models.ErrStatusConflict
+				},
+			}
+			tt.setup(store)
+			mockSender := &MockSender{
+				SendFunc: func(ctx context.Context, notification models.Notification) error { return tt.send },
+			}
+
+			err := New(store, &MockQueue{}, mockSender, RetryPolicy{MaxAttempts: 5}, tt.limiter).Handle(context.Background(), "test-uuid", -1)
+			if !errors.Is(err, storeErr) {
+				t.Fatalf("Expected store error, got %v", err)
+			}
+			if status != tt.status {
+				t.Errorf("Expected notification to be released to %s, got %s", tt.status, status)
+			}
+		})
+	}
+}
+
+// TestWorker_HandleQuietHours tests deferral of deliveries during quiet hours of the recipient
+func TestWorker_HandleQuietHours(t *testing.T) {
+	// окно тишины вокруг текущего времени в UTC
+	now := time.Now().UTC()
+	quiet := models.QuietWindow{Start: now.Add(-time.Hour).Format("15:04"), End: now.Add(time.Hour).Format("15:04")}
+	loud := models.QuietWindow{Start: now.Add(time.Hour).Format("15:04"), End: now.Add(2 * time.Hour).Format("15:04")}
+
+	tests := []struct {
+		name           string
+		priority       string
+		window         models.QuietWindow
+		prefsErr       error
+		limiter        *MockLimiter
+		expectSend     bool
+		expectedReason string
+	}{
+		{
+			name:           "Delivery in quiet hours is deferred",
+			window:         quiet,
+			expectedReason: models.ReasonQuietHours,
+		},
+		{
+			name:       "Urgent delivery bypasses quiet hours",
+			priority:   models.PriorityUrgent,
+			window:     quiet,
+			expectSend: true,
+		},
+		{
+			name:       "Delivery outside quiet hours",
+			window:     loud,
+			expectSend: true,
+		},
+		{
+			name:       "Recipient without preferences",
+			prefsErr:   models.ErrNotFound,
+			expectSend: true,
+		},
+		{
+			name:       "Preferences failure does not block delivery",
+			prefsErr:   errors.New("redis connection failed"),
+			expectSend: true,
+		},
+		{
+			name:           "Rate limited delivery records its reason",
+			window:         loud,
+			limiter:        &MockLimiter{wait: time.Second},
+			expectedReason: models.ReasonRateLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &MockStore{
+				GetMessageFunc: func(ctx context.Context, uuid string) (models.Notification, error) {
+					return models.Notification{UUID: uuid, Status: models.StatusPending, NotificationCard: models.NotificationCard{
+						Channel: models.ChannelEmail, Recipient: "user@example.com", Priority: tt.priority,
+					}}, nil
+				},
+				GetPreferencesFunc: func(ctx context.Context, channel string, address string) (models.Preferences, error) {
+					if channel != models.ChannelEmail || address != "user@example.com" {
+						t.Errorf("Unexpected preferences lookup %s:%s", channel, address)
+					}
+					if tt.prefsErr != nil {
+						return models.Preferences{}, tt.prefsErr
+					}
+					return models.Preferences{Timezone: "UTC", QuietHours: []models.QuietWindow{tt.window}}, nil
+				},
+			}
+			queue := &MockQueue{}
+			sent := false
+			mockSender := &MockSender{
+				SendFunc: func(ctx context.Context, notification models.Notification) error {
+					sent = true
+					return nil
+				},
+			}
+			var limiter Limiter
+			if tt.limiter != nil {
+				limiter = tt.limiter
+			}
+
+			err := New(store, queue, mockSender, RetryPolicy{MaxAttempts: 5}, limiter).Handle(context.Background(), "test-uuid", -1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sent != tt.expectSend {
+				t.Errorf("Expected send: %v, got %v", tt.expectSend, sent)
+			}
+			if tt.expectedReason == "" {
+				return
+			}
+			if fmt.Sprint(store.reasons) != fmt.Sprint([]string{tt.expectedReason}) {
+				t.Errorf("Expected reason %s, got %v", tt.expectedReason, store.reasons)
+			}
+			if len(queue.sent) != 1 || queue.sent[0].ScheduledAt <= 0 {
+				t.Fatalf("Expected delayed republish, got %v", queue.sent)
+			}
+			if tt.expectedReason == models.ReasonQuietHours && store.fireAt.Before(now.Add(59*time.Minute)) {
+				t.Errorf("Expected deferral to the end of quiet window, got %s", store.fireAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"DelayedNotifier/internal/app"
+	"DelayedNotifier/internal/config"
+	"DelayedNotifier/internal/memory"
+	"DelayedNotifier/internal/scheduler"
+	"context"
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+var (
+	// baseURL адрес сервиса, запущенного в TestMain
+	baseURL string
+	// store и queue бэкенды сервиса для проверок в обход API
+	store app.Store
+	queue *memory.Queue
+)
+
+// TestMain запускает весь сервис в процессе теста на бэкендах в памяти:
+// Redis и RabbitMQ для e2e тестов не нужны
+func TestMain(m *testing.M) {
+	var cfg config.Config
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		log.Fatalf("Failed to read config defaults: %s", err)
+	}
+	cfg.Storage.Backend = app.StorageMemory
+	cfg.Scheduler.Backend = scheduler.BackendMemory
+	cfg.Outbox.PollInterval = 20 * time.Millisecond
+	cfg.Callback.Secret = "e2e-callback-secret"
+	cfg.Callback.PollInterval = 20 * time.Millisecond
+	cfg.Worker.RetryBaseDelay = 100 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service, err := app.New(ctx, &cfg)
+	if err != nil {
+		log.Fatalf("Failed to init service: %s", err)
+	}
+	store = service.Store()
+	queue = service.Queue().(*memory.Queue)
+	go func() {
+		if err := service.Run(ctx); err != nil {
+			log.Fatalf("Service is stopped: %s", err)
+		}
+	}()
+
+	server := httptest.NewServer(service.Handler())
+	baseURL = server.URL
+
+	code := m.Run()
+
+	server.Close()
+	cancel()
+	service.Close()
+	os.Exit(code)
+}